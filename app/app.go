@@ -1,11 +1,20 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/canstand/ctlcheck/ctl"
+	"github.com/carlmjohnson/exitcode"
 	"github.com/carlmjohnson/flagext"
 	"github.com/pterm/pterm"
 	"gopkg.in/yaml.v3"
@@ -13,13 +22,24 @@ import (
 
 const AppName = "ctlcheck"
 
+const (
+	formatConsole = "console"
+	formatJSON    = "json"
+	formatSARIF   = "sarif"
+)
+
 func CLI(args []string) error {
 	var app appEnv
 	err := app.ParseArgs(args)
 	if err != nil {
-		return err
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return exitcode.Set(err, ExitConfig)
 	}
-	if err = app.Exec(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err = app.Exec(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 	}
 	return err
@@ -28,19 +48,57 @@ func CLI(args []string) error {
 func (app *appEnv) ParseArgs(args []string) error {
 	fl := flag.NewFlagSet(AppName, flag.ContinueOnError)
 	app.AppleCTL = ctl.NewAppleCTL()
+	app.ChromeCTL = ctl.NewChromeCTL()
 	app.MicrosoftCTL = ctl.NewMicrosoftCTL()
 	app.MozillaCTL = ctl.NewMozillaCTL()
+	app.OpenJDKCTL = ctl.NewOpenJDKCTL()
+	app.InternalCTL = ctl.NewInternalCTL()
+	sources, err := app.newSources()
+	if err != nil {
+		return err
+	}
+	app.Sources = sources
 	app.Allow = ctl.Entrys{}
+	app.failOn = failOnPolicy{}
+	app.vendor = defaultVendor
+	app.http = ctl.DefaultHTTPOptions
+	app.expiryWindow = ctl.DefaultExpiryWindow
 
 	var (
 		offline bool
 		save    bool
 		raw     bool
+		matrix  bool
 	)
 
 	fl.BoolVar(&offline, "offline", false, "load data from ctlcheck.yml instead of fetch from CCADB")
 	fl.BoolVar(&save, "save", false, "save data to ctlcheck.yml")
+	fl.Var(flagext.Choice(&app.vendor, app.vendorNames()...), "vendor", fmt.Sprintf("`name` of the vendor whose CTL is checked against: %s", strings.Join(app.vendorNames(), ", ")))
+	fl.BoolVar(&matrix, "matrix", false, "check against the CTLs of all vendors and print a trust matrix, cannot be combined with -vendor")
+	fl.Var(app.failOn, "fail-on", "comma separated `findings` that make ctlcheck exit non-zero: partial, removed, unknown, expired, expiring, not-yet-valid, missing")
+	fl.Func("expiry-window", fmt.Sprintf("number of `days` before their expiry in which certificates are reported as expiring (default %d)", ctl.DefaultExpiryWindow/(24*time.Hour)), func(val string) error {
+		days, err := strconv.Atoi(val)
+		if err != nil || days < 0 {
+			return fmt.Errorf("%q is not a number of days", val)
+		}
+		app.expiryWindow = time.Duration(days) * 24 * time.Hour
+		return nil
+	})
+	flagext.StringsVar(fl, &app.bundles, "bundle", "check the CA bundle `file` (PEM, DER or PKCS #7) instead of the system root CAs, may be repeated")
+	flagext.StringsVar(fl, &app.dirs, "dir", "check all CA bundle files in the `directory` instead of the system root CAs, may be repeated")
+	fl.StringVar(&app.image, "image", "", "check the CA bundle of a container image, given as an OCI image layout `path` or a docker save tarball")
+	fl.StringVar(&app.keystore, "keystore", "", "check the trusted certificates of a Java keystore `file` (JKS or PKCS #12), e.g. $JAVA_HOME/lib/security/cacerts")
+	fl.StringVar(&app.storepass, "storepass", ctl.DefaultKeyStorePassword, "`password` of the Java keystore, empty to skip the integrity check of JKS keystores")
+	fl.StringVar(&app.authroot, "authroot", "", "read the Microsoft authroot.stl from a local authrootstl.cab or authroot.stl `file` instead of Windows Update")
+	fl.StringVar(&app.internalPath, "internal-ctl", "", "read the internal CTL, checked with -vendor internal, from a YAML `file` or a CA bundle file or directory")
+	fl.StringVar(&app.openjdkPath, "openjdk-cacerts", "", "read the OpenJDK CTL from a jdk checkout, cacerts directory or keystore at `path` instead of GitHub")
+	fl.DurationVar(&app.http.Timeout, "timeout", app.http.Timeout, "`duration` after which fetching a CTL source fails, including retries, 0 for no timeout")
+	fl.IntVar(&app.http.Retries, "retries", app.http.Retries, "`number` of retries of a failed fetch, with exponential backoff")
+	fl.StringVar(&app.http.Proxy, "proxy", "", "`url` of the proxy to fetch the CTLs through (default from HTTPS_PROXY, HTTP_PROXY and NO_PROXY)")
+	fl.StringVar(&app.http.CAFile, "fetch-ca", "", "CA bundle `file` trusted in addition to the system root CAs when fetching the CTLs, e.g. of a TLS-inspecting proxy")
 	fl.BoolVar(&raw, "raw", false, "print unstyled raw output (set it if output is written to a file)")
+	app.format = formatConsole
+	fl.Var(flagext.Choice(&app.format, formatConsole, formatJSON, formatSARIF), "format", "output `format`: console, json or sarif")
 
 	fl.Usage = func() {
 		fmt.Fprintf(fl.Output(), `ctlcheck - %s
@@ -62,22 +120,66 @@ Options:
 	}
 	app.offline = offline
 	app.save = save
+	app.matrix = matrix
+	if matrix {
+		vendorSet := false
+		fl.Visit(func(f *flag.Flag) { vendorSet = vendorSet || f.Name == "vendor" })
+		if vendorSet {
+			err := errors.New("-matrix checks against all vendors, it cannot be combined with -vendor")
+			fmt.Fprintln(fl.Output(), err)
+			return err
+		}
+		app.vendor = vendorAll
+	}
 	if raw {
 		pterm.DisableStyling()
 	}
+	if app.format != formatConsole {
+		// keep stdout clean for the machine-readable report
+		pterm.SetDefaultOutput(os.Stderr)
+	}
+	app.http.Warnf = func(format string, args ...any) {
+		pterm.Warning.Printfln(format, args...)
+	}
+	// a bad -proxy or -fetch-ca is a configuration error, not a failed fetch
+	client, err := ctl.NewHTTPClient(app.http)
+	if err != nil {
+		fmt.Fprintln(fl.Output(), err)
+		return err
+	}
+	app.client = client
 	return nil
 }
 
 type appEnv struct {
 	AppleCTL     *ctl.AppleCTL     `yaml:"apple_ctl,omitempty"`
+	ChromeCTL    *ctl.ChromeCTL    `yaml:"chrome_ctl,omitempty"`
 	MicrosoftCTL *ctl.MicrosoftCTL `yaml:"micrsoft_ctl,omitempty"`
 	MozillaCTL   *ctl.MozillaCTL   `yaml:"mozilla_ctl,omitempty"`
+	OpenJDKCTL   *ctl.OpenJDKCTL   `yaml:"openjdk_ctl,omitempty"`
+	InternalCTL  *ctl.InternalCTL  `yaml:"internal_ctl,omitempty"`
+	Sources      sourceMap         `yaml:"sources,omitempty"`
 	Allow        ctl.Entrys        `yaml:"allow,omitempty"`
 	offline      bool              `yaml:"-"`
 	save         bool              `yaml:"-"`
+	format       string            `yaml:"-"`
+	failOn       failOnPolicy      `yaml:"-"`
+	vendor       string            `yaml:"-"`
+	matrix       bool              `yaml:"-"`
+	bundles      []string          `yaml:"-"`
+	dirs         []string          `yaml:"-"`
+	image        string            `yaml:"-"`
+	keystore     string            `yaml:"-"`
+	storepass    string            `yaml:"-"`
+	openjdkPath  string            `yaml:"-"`
+	internalPath string            `yaml:"-"`
+	authroot     string            `yaml:"-"`
+	http         ctl.HTTPOptions   `yaml:"-"`
+	client       *http.Client      `yaml:"-"`
+	expiryWindow time.Duration     `yaml:"-"`
 }
 
-func (app *appEnv) Exec() (err error) {
+func (app *appEnv) Exec(ctx context.Context) (err error) {
 	spinnerLoading, _ := pterm.DefaultSpinner.Start("Load CTL...")
 
 	if app.offline {
@@ -85,17 +187,39 @@ func (app *appEnv) Exec() (err error) {
 		err = app.Load("ctlcheck.yml")
 		if err != nil {
 			spinnerLoading.Fail(err)
-			return err
+			return exitcode.Set(err, ExitConfig)
+		}
+		err = app.checkLoaded()
+		if err != nil {
+			spinnerLoading.Fail(err)
+			return exitcode.Set(err, ExitConfig)
 		}
 	} else {
-		_ = app.Load("ctlcheck.yml") // load allow items if file exist
+		err = app.Load("ctlcheck.yml") // load allow items if file exist
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			spinnerLoading.Fail(err)
+			return exitcode.Set(err, ExitConfig)
+		}
 
 		spinnerLoading.UpdateText("Fetch CTL...")
 
-		err = app.fetchCtl()
+		if app.openjdkPath != "" {
+			app.OpenJDKCTL.Path = app.openjdkPath
+		}
+		app.OpenJDKCTL.StorePass = app.storepass // also for a path saved in ctlcheck.yml
+		if app.internalPath != "" {
+			app.InternalCTL.Path = app.internalPath
+		}
+		if app.authroot != "" {
+			app.MicrosoftCTL.AuthrootPath = app.authroot
+		}
+
+		err = app.fetchCtl(ctx, app.selectedVendors(), func(status string) {
+			spinnerLoading.UpdateText("Fetch CTL... " + status)
+		})
 		if err != nil {
 			spinnerLoading.Fail(err)
-			return err
+			return exitcode.Set(err, ExitFetch)
 		}
 		if app.save {
 			spinnerLoading.UpdateText("Fetch CTL..., save to file")
@@ -108,17 +232,38 @@ func (app *appEnv) Exec() (err error) {
 	}
 	spinnerLoading.Success()
 
-	roots, err := ctl.LoadSystemRoots()
+	roots, title, err := app.loadCerts()
 	if err != nil {
-		pterm.PrintOnErrorf("load system root CAs failed: %v", err)
+		pterm.PrintOnErrorf("%v", err)
 		return err
 	}
 	results := app.verify(roots.Certs, app.Allow)
 
-	pterm.DefaultSection.WithLevel(2).Print("System Root CA")
-	pterm.Print(results.ConsoleReport())
+	switch app.format {
+	case formatJSON:
+		err = ctl.WriteJSONReport(os.Stdout, results...)
+	case formatSARIF:
+		err = ctl.WriteSARIFReport(os.Stdout, app.artifacts(), results...)
+	default:
+		if app.matrix {
+			pterm.DefaultSection.WithLevel(2).Printf("%s - Trust Matrix", title)
+			pterm.Print(ctl.MatrixConsoleReport(results...))
+			break
+		}
+		for _, result := range results {
+			if len(results) > 1 {
+				pterm.DefaultSection.WithLevel(2).Printf("%s - %s", title, result.Vendor)
+			} else {
+				pterm.DefaultSection.WithLevel(2).Print(title)
+			}
+			pterm.Print(result.ConsoleReport())
+		}
+	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	return app.failOn.check(results...)
 }
 
 // Save as yaml file
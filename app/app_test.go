@@ -0,0 +1,36 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/exitcode"
+)
+
+func TestCLI_matrix(t *testing.T) {
+	if err := CLI([]string{"-matrix", "-vendor", "mozilla"}); exitcode.Get(err) != ExitConfig {
+		t.Errorf("CLI(-matrix -vendor mozilla) = %v (exit %d), want exit %d", err, exitcode.Get(err), ExitConfig)
+	}
+
+	var app appEnv
+	if err := app.ParseArgs([]string{"-matrix"}); err != nil || app.vendor != vendorAll {
+		t.Errorf("appEnv.ParseArgs(-matrix) error = %v, vendor = %q, want %q", err, app.vendor, vendorAll)
+	}
+}
+
+func TestCLI_httpOptions(t *testing.T) {
+	for _, args := range [][]string{
+		{"-fetch-ca", filepath.Join(t.TempDir(), "missing.pem")},
+		{"-proxy", "proxy.example.com:3128"},
+		{"-proxy", "http://[::1"},
+	} {
+		if err := CLI(args); exitcode.Get(err) != ExitConfig {
+			t.Errorf("CLI(%q) = %v (exit %d), want exit %d", args, err, exitcode.Get(err), ExitConfig)
+		}
+	}
+
+	var app appEnv
+	if err := app.ParseArgs([]string{"-proxy", "http://proxy.example.com:3128"}); err != nil || app.client == nil {
+		t.Errorf("appEnv.ParseArgs(-proxy) error = %v, client = %v, want a client", err, app.client)
+	}
+}
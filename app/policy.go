@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canstand/ctlcheck/ctl"
+	"github.com/carlmjohnson/exitcode"
+)
+
+// Exit codes returned by CLI, in addition to 0 (success) and 1 (other errors).
+const (
+	ExitConfig   = 2 // invalid flags or config file
+	ExitFetch    = 3 // fetch or load of the CTL failed
+	ExitFindings = 4 // the -fail-on policy is violated
+)
+
+const (
+	failOnPartial     = "partial"
+	failOnRemoved     = "removed"
+	failOnUnknown     = "unknown"
+	failOnExpired     = "expired"
+	failOnMissing     = "missing"
+	failOnExpiring    = "expiring"
+	failOnNotYetValid = "not-yet-valid"
+)
+
+// failOnPolicy is the set of findings that make the check fail.
+type failOnPolicy map[string]bool
+
+func (p failOnPolicy) Set(val string) error {
+	for _, v := range strings.Split(val, ",") {
+		v = strings.TrimSpace(v)
+		switch v {
+		case "":
+			continue
+		case failOnPartial, failOnRemoved, failOnUnknown, failOnExpired, failOnExpiring, failOnNotYetValid, failOnMissing:
+			p[v] = true
+		default:
+			return fmt.Errorf("%q not in %s, %s, %s, %s, %s, %s, %s", v, failOnPartial, failOnRemoved, failOnUnknown, failOnExpired, failOnExpiring, failOnNotYetValid, failOnMissing)
+		}
+	}
+	return nil
+}
+
+func (p failOnPolicy) String() string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// check returns an error with ExitFindings code if any result violates the policy
+func (p failOnPolicy) check(results ...*ctl.VerifyResult) error {
+	var findings []string
+	// the expiry of a certificate is counted once, not once per vendor
+	expired, expiring, notYetValid := map[string]bool{}, map[string]bool{}, map[string]bool{}
+	now := time.Now()
+	for _, result := range results {
+		if p[failOnPartial] && len(result.PartialCerts) > 0 {
+			findings = append(findings, fmt.Sprintf("%d partially distrusted by %s", len(result.PartialCerts), result.Vendor))
+		}
+		if p[failOnRemoved] && len(result.RemovedCerts) > 0 {
+			findings = append(findings, fmt.Sprintf("%d removed by %s", len(result.RemovedCerts), result.Vendor))
+		}
+		if p[failOnUnknown] && len(result.UnknownCerts) > 0 {
+			findings = append(findings, fmt.Sprintf("%d unknown to %s", len(result.UnknownCerts), result.Vendor))
+		}
+		if p[failOnMissing] && len(result.MissingEntries) > 0 {
+			findings = append(findings, fmt.Sprintf("%d trusted by %s missing", len(result.MissingEntries), result.Vendor))
+		}
+		if p[failOnExpired] {
+			for _, cert := range result.Expired(now) {
+				expired[cert.Checksum] = true
+			}
+		}
+		if p[failOnExpiring] {
+			for _, cert := range result.Expiring(now, result.ExpiryWindow) {
+				expiring[cert.Checksum] = true
+			}
+		}
+		if p[failOnNotYetValid] {
+			for _, cert := range result.NotYetValid(now) {
+				notYetValid[cert.Checksum] = true
+			}
+		}
+	}
+	if len(expired) > 0 {
+		findings = append(findings, fmt.Sprintf("%d expired", len(expired)))
+	}
+	if len(expiring) > 0 {
+		findings = append(findings, fmt.Sprintf("%d expiring", len(expiring)))
+	}
+	if len(notYetValid) > 0 {
+		findings = append(findings, fmt.Sprintf("%d not yet valid", len(notYetValid)))
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	return exitcode.Set(fmt.Errorf("found certificates: %s", strings.Join(findings, ", ")), ExitFindings)
+}
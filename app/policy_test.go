@@ -0,0 +1,62 @@
+package app
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canstand/ctlcheck/ctl"
+	"github.com/carlmjohnson/exitcode"
+)
+
+func Test_failOnPolicy_check(t *testing.T) {
+	now := time.Now()
+	cert := func(checksum string, notBefore, notAfter time.Time) *ctl.Cert {
+		return &ctl.Cert{Certificate: &x509.Certificate{NotBefore: notBefore, NotAfter: notAfter}, Checksum: checksum}
+	}
+	certs := []*ctl.Cert{
+		cert("AA", now.Add(-time.Hour), now.Add(365*24*time.Hour)),
+		cert("BB", now.Add(-time.Hour), now.Add(10*24*time.Hour)),
+		cert("CC", now.Add(24*time.Hour), now.Add(365*24*time.Hour)),
+	}
+	trusted := ctl.NewCTL()
+	for _, c := range certs {
+		trusted.Trusted[c.Checksum] = ctl.Entry{Name: c.Checksum}
+	}
+	result := trusted.VerifyAs("Test", certs, ctl.Entrys{})
+
+	tests := []struct {
+		policy      string
+		window      time.Duration
+		wantFinding string
+	}{
+		{policy: "removed,unknown,expired", window: ctl.DefaultExpiryWindow},
+		{policy: "expiring", window: ctl.DefaultExpiryWindow, wantFinding: "1 expiring"},
+		{policy: "expiring", window: 24 * time.Hour},
+		{policy: "not-yet-valid", window: ctl.DefaultExpiryWindow, wantFinding: "1 not yet valid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			p := failOnPolicy{}
+			if err := p.Set(tt.policy); err != nil {
+				t.Fatalf("failOnPolicy.Set(%q) error = %v", tt.policy, err)
+			}
+			result.ExpiryWindow = tt.window
+			err := p.check(result)
+			if tt.wantFinding == "" {
+				if err != nil {
+					t.Errorf("failOnPolicy.check() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantFinding) || exitcode.Get(err) != ExitFindings {
+				t.Errorf("failOnPolicy.check() error = %v, want %q with exit code %d", err, tt.wantFinding, ExitFindings)
+			}
+		})
+	}
+
+	if err := (failOnPolicy{}).Set("soon"); err == nil {
+		t.Errorf("failOnPolicy.Set() of an unknown finding, want error")
+	}
+}
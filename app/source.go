@@ -0,0 +1,73 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/canstand/ctlcheck/ctl"
+	"github.com/carlmjohnson/exitcode"
+)
+
+// loadCerts loads the certificates to check: the CA bundle of the container
+// image given with -image, the Java keystore given with -keystore, the CA
+// bundles given with -bundle and -dir, or the system root CAs if there are
+// none. It also returns the title of the report.
+func (app *appEnv) loadCerts() (*ctl.CertStore, string, error) {
+	sources := 0
+	for _, given := range []bool{app.image != "", app.keystore != "", len(app.bundles) > 0 || len(app.dirs) > 0} {
+		if given {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, "", exitcode.Set(fmt.Errorf("only one of -image, -keystore or -bundle/-dir can be given"), ExitConfig)
+	}
+
+	switch {
+	case app.image != "":
+		roots, err := ctl.LoadImageRoots(app.image)
+		if err != nil {
+			return nil, "", exitcode.Set(fmt.Errorf("load container image failed: %w", err), ExitConfig)
+		}
+		return roots, "Image Root CA", nil
+	case app.keystore != "":
+		roots, err := ctl.LoadKeyStore(app.keystore, app.storepass)
+		if err != nil {
+			return nil, "", exitcode.Set(fmt.Errorf("load Java keystore failed: %w", err), ExitConfig)
+		}
+		return roots, "Java KeyStore", nil
+	case len(app.bundles) == 0 && len(app.dirs) == 0:
+		roots, err := ctl.LoadSystemRoots()
+		if err != nil {
+			return nil, "", fmt.Errorf("load system root CAs failed: %w", err)
+		}
+		return roots, "System Root CA", nil
+	}
+
+	store := ctl.NewCertStore()
+	if err := store.LoadBundleFiles(app.bundles...); err != nil {
+		return nil, "", exitcode.Set(fmt.Errorf("load CA bundle failed: %w", err), ExitConfig)
+	}
+	for _, dir := range app.dirs {
+		if err := store.LoadBundleDir(dir); err != nil {
+			return nil, "", exitcode.Set(fmt.Errorf("load CA bundle directory failed: %w", err), ExitConfig)
+		}
+	}
+	if len(store.Certs) == 0 {
+		return nil, "", exitcode.Set(fmt.Errorf("no certificates found in the CA bundles"), ExitConfig)
+	}
+	return store, "CA Bundle", nil
+}
+
+// artifacts returns the paths of the certificates loaded by loadCerts, none
+// for the system root CAs, which may be read from several files or an OS API
+func (app *appEnv) artifacts() []string {
+	switch {
+	case app.image != "":
+		return []string{app.image}
+	case app.keystore != "":
+		return []string{app.keystore}
+	case len(app.bundles) == 0 && len(app.dirs) == 0:
+		return nil
+	}
+	return append(append([]string{}, app.bundles...), app.dirs...)
+}
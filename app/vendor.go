@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/canstand/ctlcheck/ctl"
+	"gopkg.in/yaml.v3"
+)
+
+// vendorAll selects every registered vendor
+const vendorAll = "all"
+
+// vendors returns all registered CTL sources in report order. The built-in
+// ones are resolved from their fields on each call, so the list stays valid
+// after Load.
+func (app *appEnv) vendors() []ctl.CTLSource {
+	builtin := []ctl.CTLSource{app.MozillaCTL, app.AppleCTL, app.MicrosoftCTL, app.ChromeCTL, app.OpenJDKCTL, app.InternalCTL}
+	ret := []ctl.CTLSource{}
+	for _, name := range ctl.Sources() {
+		for _, s := range builtin {
+			if s.Name() == name {
+				ret = append(ret, s)
+			}
+		}
+		if s, ok := app.Sources[name]; ok {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// newSources returns the registered CTL sources other than the built-in ones
+func (app *appEnv) newSources() (sourceMap, error) {
+	ret := sourceMap{}
+	for _, name := range ctl.Sources() {
+		switch name {
+		case ctl.MOZILLA, ctl.APPLE, ctl.MICROSOFT, ctl.CHROME, ctl.OPENJDK, ctl.INTERNAL:
+			continue
+		}
+		s, err := ctl.NewSource(name)
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = s
+	}
+	return ret, nil
+}
+
+// sourceMap holds the registered CTL sources other than the built-in ones by
+// name. Loading keeps the state of sources missing in the config file and
+// skips sources that are not registered.
+type sourceMap map[string]ctl.CTLSource
+
+func (m sourceMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: sources must be a mapping of source names", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		if s, ok := m[name]; ok {
+			if err := node.Content[i+1].Decode(s); err != nil {
+				return fmt.Errorf("source %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// vendorNames returns the names accepted by -vendor
+func (app *appEnv) vendorNames() []string {
+	names := []string{}
+	for _, v := range app.vendors() {
+		names = append(names, v.Name())
+	}
+	return append(names, vendorAll)
+}
+
+// selectedVendors returns the vendors selected with -vendor. All vendors
+// include the internal CTL only if one is configured.
+func (app *appEnv) selectedVendors() []ctl.CTLSource {
+	ret := []ctl.CTLSource{}
+	for _, v := range app.vendors() {
+		switch {
+		case app.vendor == v.Name():
+			ret = append(ret, v)
+		case app.vendor == vendorAll:
+			if v == ctl.CTLSource(app.InternalCTL) && app.InternalCTL.Path == "" && len(app.InternalCTL.Trusted) == 0 {
+				continue
+			}
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// maxConcurrentVendors bounds the number of CTLs fetched at a time
+const maxConcurrentVendors = 3
+
+// fetchCtl fetches the CTLs of vendors concurrently. progress is called with
+// the status of all vendors whenever one of them changes, and the errors of
+// all vendors that failed are returned.
+func (app *appEnv) fetchCtl(ctx context.Context, vendors []ctl.CTLSource, progress func(status string)) error {
+	states := make([]string, len(vendors))
+	errs := make([]error, len(vendors))
+	var mu sync.Mutex
+	setState := func(i int, state string) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[i] = state
+		status := []string{}
+		for j, v := range vendors {
+			status = append(status, fmt.Sprintf("%s %s", v.Name(), states[j]))
+		}
+		progress(strings.Join(status, ", "))
+	}
+
+	sem := make(chan struct{}, maxConcurrentVendors)
+	var wg sync.WaitGroup
+	for i := range vendors {
+		setState(i, "waiting")
+	}
+	for i, v := range vendors {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, v ctl.CTLSource) {
+			defer func() { <-sem; wg.Done() }()
+			setState(i, "fetching")
+			if err := v.Fetch(ctx, app.client); err != nil {
+				errs[i] = fmt.Errorf("fetch %s CTL: %w", v.Name(), err)
+				setState(i, "failed")
+				return
+			}
+			setState(i, "done")
+		}(i, v)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// checkLoaded reports an error if the CTL of a selected vendor is missing in the config file
+func (app *appEnv) checkLoaded() error {
+	for _, v := range app.selectedVendors() {
+		if c := v.TrustList(); c == nil || len(c.Trusted) == 0 {
+			return fmt.Errorf("no %s CTL in the config file, run with -save first", v.Name())
+		}
+	}
+	return nil
+}
+
+func (app *appEnv) verify(certs []*ctl.Cert, allowedCerts ctl.Entrys) []*ctl.VerifyResult {
+	results := []*ctl.VerifyResult{}
+	for _, v := range app.selectedVendors() {
+		result := v.Verify(certs, allowedCerts)
+		result.ExpiryWindow = app.expiryWindow
+		results = append(results, result)
+	}
+	return results
+}
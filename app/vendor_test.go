@@ -0,0 +1,88 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canstand/ctlcheck/ctl"
+)
+
+// fakeSource is a CTLSource whose Fetch fails with err after delay
+type fakeSource struct {
+	*ctl.CTL
+	name    string
+	delay   time.Duration
+	err     error
+	fetched atomic.Bool
+}
+
+func (s *fakeSource) Name() string   { return s.name }
+func (s *fakeSource) Vendor() string { return s.name }
+func (s *fakeSource) Fetch(ctx context.Context, cl *http.Client) error {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if s.err != nil {
+		return s.err
+	}
+	s.fetched.Store(true)
+	return nil
+}
+
+func (s *fakeSource) Verify(certs []*ctl.Cert, allowedCerts ctl.Entrys) *ctl.VerifyResult {
+	return s.VerifyAs(s.Vendor(), certs, allowedCerts)
+}
+func (s *fakeSource) TrustList() *ctl.CTL { return s.CTL }
+
+func Test_appEnv_fetchCtl(t *testing.T) {
+	failures := map[string]error{
+		"first": errors.New("first is down"),
+		"third": errors.New("third is down"),
+	}
+	sources := []*fakeSource{}
+	vendors := []ctl.CTLSource{}
+	for i, name := range []string{"first", "second", "third", "fourth", "fifth", "sixth"} {
+		s := &fakeSource{CTL: ctl.NewCTL(), name: name, delay: time.Duration(i) * time.Millisecond, err: failures[name]}
+		sources = append(sources, s)
+		vendors = append(vendors, s)
+	}
+
+	var app appEnv
+	var calls atomic.Int32
+	var last atomic.Value
+	err := app.fetchCtl(context.Background(), vendors, func(status string) {
+		calls.Add(1)
+		last.Store(status)
+	})
+
+	if err == nil {
+		t.Fatalf("appEnv.fetchCtl() error = nil, want the errors of the failed vendors")
+	}
+	for name, failure := range failures {
+		if !errors.Is(err, failure) || !strings.Contains(err.Error(), fmt.Sprintf("fetch %s CTL", name)) {
+			t.Errorf("appEnv.fetchCtl() error = %v, want the error of %s", err, name)
+		}
+	}
+	for _, s := range sources {
+		if fetched := s.fetched.Load(); fetched != (s.err == nil) {
+			t.Errorf("appEnv.fetchCtl() fetched %s = %v, want %v", s.name, fetched, s.err == nil)
+		}
+	}
+
+	// waiting, fetching and done or failed for each vendor
+	if n := calls.Load(); n != int32(3*len(vendors)) {
+		t.Errorf("appEnv.fetchCtl() called progress %d times, want %d", n, 3*len(vendors))
+	}
+	want := "first failed, second done, third failed, fourth done, fifth done, sixth done"
+	if status, _ := last.Load().(string); status != want {
+		t.Errorf("appEnv.fetchCtl() last progress = %q, want %q", status, want)
+	}
+}
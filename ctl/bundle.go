@@ -0,0 +1,110 @@
+package ctl
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/github/smimesign/ietf-cms/protocol"
+)
+
+// AppendCertsFromDER attempts to parse one or more concatenated DER encoded
+// certificates, as found in .cer/.crt files. It reports whether any
+// certificates were successfully parsed.
+func (s *CertStore) AppendCertsFromDER(der []byte) (ok bool) {
+	certs, err := x509.ParseCertificates(der)
+	if err != nil {
+		return false
+	}
+	for _, cert := range certs {
+		s.AddCert(cert)
+		ok = true
+	}
+	return ok
+}
+
+// AppendCertsFromPKCS7 attempts to parse a DER or BER encoded PKCS #7
+// (CMS SignedData) bundle, as found in .p7b/.p7c files. It reports whether
+// any certificates were successfully parsed.
+func (s *CertStore) AppendCertsFromPKCS7(ber []byte) (ok bool) {
+	ci, err := protocol.ParseContentInfo(ber)
+	if err != nil {
+		return false
+	}
+	sd, err := ci.SignedDataContent()
+	if err != nil {
+		return false
+	}
+	certs, err := sd.X509Certificates()
+	if err != nil {
+		return false
+	}
+	for _, cert := range certs {
+		s.AddCert(cert)
+		ok = true
+	}
+	return ok
+}
+
+// AppendCertsFromBundle attempts to parse a CA bundle in any supported
+// format: PEM (certificates and PKCS #7 blocks), PKCS #7 or DER. It reports
+// whether any certificates were successfully parsed.
+func (s *CertStore) AppendCertsFromBundle(data []byte) (ok bool) {
+	if !bytes.Contains(data, []byte("-----BEGIN ")) {
+		return s.AppendCertsFromPKCS7(data) || s.AppendCertsFromDER(data)
+	}
+
+	ok = s.AppendCertsFromPEM(data)
+	for rest := data; len(rest) > 0; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "PKCS7" && s.AppendCertsFromPKCS7(block.Bytes) {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// LoadBundleFiles loads the certificates of CA bundle files, see AppendCertsFromBundle.
+// It fails if a file can not be read or contains no certificates.
+func (s *CertStore) LoadBundleFiles(files ...string) error {
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if !s.AppendCertsFromBundle(data) {
+			return fmt.Errorf("no certificates found in %s", file)
+		}
+	}
+	return nil
+}
+
+// LoadBundleDir loads the certificates of all CA bundle files in dir and its
+// subdirectories. Files that contain no certificates are skipped.
+func (s *CertStore) LoadBundleDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if d.Type()&fs.ModeSymlink != 0 {
+				return nil // dangling or pointing to a directory
+			}
+			return err
+		}
+		s.AppendCertsFromBundle(data)
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package ctl
+
+import (
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/smimesign/ietf-cms/protocol"
+)
+
+func TestCertStore_AppendCertsFromBundle(t *testing.T) {
+	c1 := newValidCert(t, "Root 1")
+	c2 := newValidCert(t, "Root 2")
+
+	eci, err := protocol.NewDataEncapsulatedContentInfo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := protocol.NewSignedData(eci)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Cert{c1, c2} {
+		if err = sd.AddCertificate(c.Certificate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p7b, err := sd.ContentInfoDER()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantCount int
+	}{
+		{"pem", append(pemCert(c1), pemCert(c2)...), 2},
+		{"der", c1.Raw, 1},
+		{"concatenated der", append(append([]byte{}, c1.Raw...), c2.Raw...), 2},
+		{"pkcs7", p7b, 2},
+		{"pem pkcs7", pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: p7b}), 2},
+		{"garbage", []byte("not a certificate"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCertStore()
+			ok := s.AppendCertsFromBundle(tt.data)
+			if ok != (tt.wantCount > 0) || len(s.Certs) != tt.wantCount {
+				t.Errorf("AppendCertsFromBundle() = %v, %d certs, want %d certs", ok, len(s.Certs), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestCertStore_LoadBundleDir(t *testing.T) {
+	c1 := newValidCert(t, "Root 1")
+	c2 := newValidCert(t, "Root 2")
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"root1.pem":     pemCert(c1),
+		"sub/root2.cer": c2.Raw,
+		"README":        []byte("not a certificate"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewCertStore()
+	if err := s.LoadBundleDir(dir); err != nil {
+		t.Fatalf("LoadBundleDir() error = %v", err)
+	}
+	if len(s.Certs) != 2 {
+		t.Errorf("LoadBundleDir() loaded %d certs, want 2", len(s.Certs))
+	}
+
+	if err := s.LoadBundleFiles(filepath.Join(dir, "README")); err == nil {
+		t.Errorf("LoadBundleFiles() error = nil, want error for a file without certificates")
+	}
+}
+
+func pemCert(c *Cert) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+}
@@ -0,0 +1,22 @@
+package ctl
+
+// CA bundle locations of Linux distributions, used for the system roots on
+// Linux and to locate the trust store in container images on any OS.
+var (
+	// Possible certificate files; stop after finding one.
+	linuxCertFiles = []string{
+		"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+		"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+		"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+		"/etc/pki/tls/cacert.pem",                           // OpenELEC
+		"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+		"/etc/ssl/cert.pem",                                 // Alpine Linux
+	}
+
+	// Possible directories with certificate files; all will be read.
+	linuxCertDirectories = []string{
+		"/etc/ssl/certs",               // SLES10/SLES11, https://golang.org/issue/12139
+		"/etc/pki/tls/certs",           // Fedora/RHEL
+		"/system/etc/security/cacerts", // Android
+	}
+)
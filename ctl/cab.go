@@ -0,0 +1,237 @@
+package ctl
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// https://learn.microsoft.com/en-us/previous-versions/bb417343(v=msdn.10)
+const (
+	cabMagic = "MSCF"
+
+	cabFlagPrevCabinet    = 0x0001
+	cabFlagNextCabinet    = 0x0002
+	cabFlagReservePresent = 0x0004
+
+	cabCompressNone  = 0
+	cabCompressMSZIP = 1
+
+	// uncompressed size of a MSZIP block, and of its dictionary
+	mszipBlockSize = 32768
+)
+
+type cabHeader struct {
+	Signature    [4]byte
+	_            uint32
+	CbCabinet    uint32
+	_            uint32
+	CoffFiles    uint32
+	_            uint32
+	VersionMinor uint8
+	VersionMajor uint8
+	CFolders     uint16
+	CFiles       uint16
+	Flags        uint16
+	SetID        uint16
+	ICabinet     uint16
+}
+
+type cabFolder struct {
+	CoffCabStart uint32
+	CCFData      uint16
+	TypeCompress uint16
+}
+
+type cabFile struct {
+	CbFile          uint32
+	UoffFolderStart uint32
+	IFolder         uint16
+	Date            uint16
+	Time            uint16
+	Attribs         uint16
+}
+
+type cabData struct {
+	Csum     uint32
+	CbData   uint16
+	CbUncomp uint16
+}
+
+// isCAB reports whether b is a Microsoft cabinet file
+func isCAB(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(cabMagic))
+}
+
+// fromCAB returns the file name in b if b is a cabinet, as Microsoft
+// distributes its CTLs, otherwise b itself.
+func fromCAB(b []byte, name string) ([]byte, error) {
+	if !isCAB(b) {
+		return b, nil
+	}
+	return readCABFile(b, name)
+}
+
+// readCABFile extracts the file name (case-insensitive) from a Microsoft
+// cabinet that is not part of a set. Only uncompressed and MSZIP folders are
+// supported, which is what Microsoft uses for its CTLs.
+func readCABFile(b []byte, name string) ([]byte, error) {
+	r := bytes.NewReader(b)
+	var hdr cabHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("cab: %w", err)
+	}
+	if string(hdr.Signature[:]) != cabMagic {
+		return nil, errors.New("cab: not a cabinet file")
+	}
+	if hdr.Flags&(cabFlagPrevCabinet|cabFlagNextCabinet) != 0 {
+		return nil, errors.New("cab: multi-cabinet sets are not supported")
+	}
+	var folderReserve, dataReserve int64
+	if hdr.Flags&cabFlagReservePresent != 0 {
+		var reserve struct {
+			CbCFHeader uint16
+			CbCFFolder uint8
+			CbCFData   uint8
+		}
+		if err := binary.Read(r, binary.LittleEndian, &reserve); err != nil {
+			return nil, fmt.Errorf("cab: %w", err)
+		}
+		if _, err := r.Seek(int64(reserve.CbCFHeader), io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("cab: %w", err)
+		}
+		folderReserve, dataReserve = int64(reserve.CbCFFolder), int64(reserve.CbCFData)
+	}
+
+	folders := make([]cabFolder, hdr.CFolders)
+	for i := range folders {
+		if err := binary.Read(r, binary.LittleEndian, &folders[i]); err != nil {
+			return nil, fmt.Errorf("cab: folder %d: %w", i, err)
+		}
+		if _, err := r.Seek(folderReserve, io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("cab: %w", err)
+		}
+	}
+
+	if _, err := r.Seek(int64(hdr.CoffFiles), io.SeekStart); err != nil {
+		return nil, fmt.Errorf("cab: %w", err)
+	}
+	for i := 0; i < int(hdr.CFiles); i++ {
+		var f cabFile
+		if err := binary.Read(r, binary.LittleEndian, &f); err != nil {
+			return nil, fmt.Errorf("cab: file %d: %w", i, err)
+		}
+		fname, err := readCString(r)
+		if err != nil {
+			return nil, fmt.Errorf("cab: file %d: %w", i, err)
+		}
+		if !strings.EqualFold(fname, name) {
+			continue
+		}
+		if int(f.IFolder) >= len(folders) {
+			return nil, fmt.Errorf("cab: %s: folder %d not found", fname, f.IFolder)
+		}
+		data, err := readCABFolder(b, folders[f.IFolder], dataReserve, int64(f.UoffFolderStart)+int64(f.CbFile))
+		if err != nil {
+			return nil, fmt.Errorf("cab: %s: %w", fname, err)
+		}
+		return data[f.UoffFolderStart:], nil
+	}
+	return nil, fmt.Errorf("cab: %s not found", name)
+}
+
+// readCABFolder decompresses the first size bytes of the folder
+func readCABFolder(b []byte, folder cabFolder, dataReserve, size int64) ([]byte, error) {
+	compress := folder.TypeCompress & 0x000F
+	if compress != cabCompressNone && compress != cabCompressMSZIP {
+		return nil, fmt.Errorf("compression type %d is not supported", compress)
+	}
+	r := bytes.NewReader(b)
+	if _, err := r.Seek(int64(folder.CoffCabStart), io.SeekStart); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	for i := 0; i < int(folder.CCFData) && int64(out.Len()) < size; i++ {
+		var d cabData
+		if err := binary.Read(r, binary.LittleEndian, &d); err != nil {
+			return nil, fmt.Errorf("data block %d: %w", i, err)
+		}
+		if _, err := r.Seek(dataReserve, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		block := make([]byte, d.CbData)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return nil, fmt.Errorf("data block %d: %w", i, err)
+		}
+		if d.Csum != 0 && d.Csum != cabDataChecksum(d, block) {
+			return nil, fmt.Errorf("data block %d: checksum mismatch", i)
+		}
+
+		if compress == cabCompressNone {
+			out.Write(block)
+			continue
+		}
+		// each MSZIP block is a deflate stream prefixed with "CK", using
+		// the previous uncompressed block as dictionary
+		if !bytes.HasPrefix(block, []byte("CK")) {
+			return nil, fmt.Errorf("data block %d: bad MSZIP signature", i)
+		}
+		dict := out.Bytes()
+		if len(dict) > mszipBlockSize {
+			dict = dict[len(dict)-mszipBlockSize:]
+		}
+		fr := flate.NewReaderDict(bytes.NewReader(block[2:]), dict)
+		n, err := io.Copy(&out, io.LimitReader(fr, mszipBlockSize))
+		fr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("data block %d: %w", i, err)
+		}
+		if n != int64(d.CbUncomp) {
+			return nil, fmt.Errorf("data block %d: %d bytes uncompressed, want %d", i, n, d.CbUncomp)
+		}
+	}
+	if int64(out.Len()) < size {
+		return nil, fmt.Errorf("folder has %d bytes, want %d", out.Len(), size)
+	}
+	return out.Bytes()[:size], nil
+}
+
+// cabDataChecksum computes the checksum of a CFDATA block, over its data
+// and then its cbData and cbUncomp fields
+func cabDataChecksum(d cabData, block []byte) uint32 {
+	var hdr [4]byte
+	binary.LittleEndian.PutUint16(hdr[0:], d.CbData)
+	binary.LittleEndian.PutUint16(hdr[2:], d.CbUncomp)
+	return cabChecksum(hdr[:], cabChecksum(block, 0))
+}
+
+func cabChecksum(b []byte, seed uint32) uint32 {
+	csum := seed
+	for ; len(b) >= 4; b = b[4:] {
+		csum ^= binary.LittleEndian.Uint32(b)
+	}
+	var ul uint32
+	for _, c := range b { // the remaining bytes, most significant first
+		ul = ul<<8 | uint32(c)
+	}
+	return csum ^ ul
+}
+
+func readCString(r io.ByteReader) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == 0 {
+			return sb.String(), nil
+		}
+		sb.WriteByte(c)
+	}
+	return "", errors.New("name too long")
+}
@@ -0,0 +1,45 @@
+package ctl
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_readCABFile(t *testing.T) {
+	stl, err := os.ReadFile("testdata/authroot.stl")
+	if err != nil {
+		t.Fatalf("readCABFile() error: %v", err)
+	}
+	cab, err := os.ReadFile("testdata/authrootstl.cab")
+	if err != nil {
+		t.Fatalf("readCABFile() error: %v", err)
+	}
+
+	got, err := readCABFile(cab, "AuthRoot.stl")
+	if err != nil {
+		t.Fatalf("readCABFile() error = %v", err)
+	}
+	if !bytes.Equal(got, stl) {
+		t.Errorf("readCABFile() = %d bytes, want the %d bytes of authroot.stl", len(got), len(stl))
+	}
+	if _, err := readCABFile(cab, "disallowedcert.stl"); err == nil {
+		t.Errorf("readCABFile() of a missing file, want error")
+	}
+	if _, err := readCABFile(stl, "authroot.stl"); err == nil {
+		t.Errorf("readCABFile() of a non cabinet file, want error")
+	}
+
+	corrupted := bytes.Clone(cab)
+	corrupted[len(corrupted)-100] ^= 0xff
+	if _, err := readCABFile(corrupted, "authroot.stl"); err == nil {
+		t.Errorf("readCABFile() of a corrupted cabinet, want error")
+	}
+
+	// the signature of the extracted list is verified as well
+	ctl := NewMicrosoftCTL()
+	if _, err := ctl.checkAuthroot(cab, time.Date(2021, 10, 20, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("MicrosoftCTL.checkAuthroot() of authrootstl.cab error = %v", err)
+	}
+}
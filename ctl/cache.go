@@ -0,0 +1,214 @@
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ctlcheck")
+}
+
+// cacheTransport caches the payloads of GET requests in dir, and only
+// downloads them again if the server reports a change (ETag or
+// Last-Modified). If the server is unreachable, the cached copy is used with
+// a warning, but error responses and untrusted servers fail the request.
+type cacheTransport struct {
+	base  http.RoundTripper
+	dir   string
+	warnf func(format string, args ...any)
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.dir == "" || req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	url := req.URL.String()
+	cached := t.load(url)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		for k, v := range cached.conditionalHeaders() {
+			req.Header[k] = v
+		}
+	}
+	res, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && cached != nil && unreachable(err):
+		t.warnf("%s is unreachable, using the cached copy fetched %s ago, it may be stale: %v",
+			url, time.Since(cached.FetchedAt).Round(time.Minute), err)
+		return cached.response(req), nil
+	case err != nil:
+		return nil, err
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+		res.Body.Close()
+		entry := *cached
+		if etag := res.Header.Get("ETag"); etag != "" { // not always sent again with 304
+			entry.ETag = etag
+		}
+		if lastModified := res.Header.Get("Last-Modified"); lastModified != "" {
+			entry.LastModified = lastModified
+		}
+		entry.FetchedAt = time.Now()
+		t.save(&entry)
+		return entry.response(req), nil
+	case res.StatusCode != http.StatusOK:
+		return res, nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	t.save(&cacheEntry{
+		URL:          url,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+		body:         body,
+	})
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// unreachable reports whether err is a network error, e.g. a failed DNS
+// lookup, a refused connection or a timeout. A rejected server certificate is
+// not, it may be a TLS interception that must not be worked around silently.
+func unreachable(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var urlErr *url.Error // a net.Error itself, whatever it wraps
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// cacheEntry is the metadata of a cached payload, the payload itself is
+// stored next to it
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+
+	body []byte
+}
+
+func (t *cacheTransport) path(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(t.dir, hex.EncodeToString(hash[:16]))
+}
+
+// load returns the cached payload of url, or nil if there is none
+func (t *cacheTransport) load(url string) *cacheEntry {
+	path := t.path(url)
+	data, err := os.ReadFile(path + ".json")
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.warnf("read cache of %s: %v", url, err)
+		}
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	if entry.body, err = os.ReadFile(path + ".body"); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// conditionalHeaders asks the server to only send the payload if it
+// changed since it was cached
+func (entry *cacheEntry) conditionalHeaders() http.Header {
+	h := http.Header{}
+	if entry == nil {
+		return h
+	}
+	if entry.ETag != "" {
+		h.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		h.Set("If-Modified-Since", entry.LastModified)
+	}
+	return h
+}
+
+// response returns the cached payload as the response to req
+func (entry *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+		Request:       req,
+	}
+}
+
+// save writes the entry to the cache, failures only produce a warning as
+// the payload was fetched anyway
+func (t *cacheTransport) save(entry *cacheEntry) {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(t.dir, 0o755)
+	}
+	path := t.path(entry.URL)
+	if err == nil {
+		err = writeFileAtomic(path+".body", entry.body)
+	}
+	if err == nil {
+		err = writeFileAtomic(path+".json", data)
+	}
+	if err != nil {
+		t.warnf("cache %s: %v", entry.URL, err)
+	}
+}
+
+// writeFileAtomic writes the file by renaming a temporary file, so that
+// concurrent fetches never read a partially written one
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package ctl
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_getBody_cache(t *testing.T) {
+	warnings := []string{}
+	opts := HTTPOptions{
+		CacheDir: t.TempDir(),
+		Warnf:    func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) },
+	}
+	cl, err := NewHTTPClient(opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+
+	hits, conditional, failing := 0, 0, false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "payload")
+	}))
+	url := srv.URL + "/report.csv"
+
+	for i := 0; i < 2; i++ {
+		got, err := getBody(context.Background(), cl, url)
+		if err != nil || string(got) != "payload" {
+			t.Fatalf("getBody() #%d = %q, %v, want %q", i, got, err, "payload")
+		}
+	}
+	if hits != 2 || conditional != 1 {
+		t.Errorf("getBody() made %d requests, %d conditional, want 2, 1", hits, conditional)
+	}
+
+	// an error response is not an unreachable server
+	failing = true
+	if _, err := getBody(context.Background(), cl, url); err == nil || len(warnings) != 0 {
+		t.Errorf("getBody() of a failing server error = %v, warnings = %q, want error without fallback", err, warnings)
+	}
+
+	srv.Close()
+	got, err := getBody(context.Background(), cl, url)
+	if err != nil || string(got) != "payload" {
+		t.Fatalf("getBody() of an unreachable server = %q, %v, want the cached %q", got, err, "payload")
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "may be stale") {
+		t.Errorf("getBody() of an unreachable server warnings = %q, want a staleness warning", warnings)
+	}
+
+	// nor is an untrusted one, e.g. a TLS-inspecting proxy
+	tlsSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "payload")
+	}))
+	tlsSrv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+	tlsURL := tlsSrv.URL + "/report.csv"
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	trusting := opts
+	trusting.CAFile = caFile
+	trustingClient, err := NewHTTPClient(trusting)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	if _, err := getBody(context.Background(), trustingClient, tlsURL); err != nil {
+		t.Fatalf("getBody() of a trusted server error = %v", err)
+	}
+	if _, err := getBody(context.Background(), cl, tlsURL); err == nil || len(warnings) != 1 {
+		t.Errorf("getBody() of an untrusted server error = %v, warnings = %q, want error without fallback", err, warnings)
+	}
+
+	opts.CacheDir = ""
+	if cl, err = NewHTTPClient(opts); err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	if _, err := getBody(context.Background(), cl, url); err == nil {
+		t.Errorf("getBody() of an unreachable server without cache, want error")
+	}
+}
@@ -10,7 +10,7 @@ type sum256 [sha256.Size]byte
 
 // Cert adds Checksum field to x509.Cerificate to store SHA256
 type Cert struct {
-	*x509.Certificate `json:"_"`
+	*x509.Certificate `json:"-"`
 	Checksum          string `json:"checksum,omitempty"`
 }
 
@@ -0,0 +1,59 @@
+package ctl
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Trust bits of a root certificate
+const (
+	TrustWebsites = "Websites"
+	TrustEmail    = "Email"
+)
+
+// usageAliases maps the usages of other vendors to trust bits
+var usageAliases = map[string]string{
+	"Server Authentication": TrustWebsites,
+	"Secure Email":          TrustEmail,
+}
+
+// Constraint restricts the trust a vendor places in an included root certificate.
+type Constraint struct {
+	EVPolicyOIDs []string `yaml:"ev_policy_oids,omitempty" json:"ev_policy_oids,omitempty"`
+	// Certificates issued after these dates are not trusted
+	DistrustTLSAfter   time.Time `yaml:"distrust_tls_after,omitempty" json:"distrust_tls_after,omitempty"`
+	DistrustEmailAfter time.Time `yaml:"distrust_email_after,omitempty" json:"distrust_email_after,omitempty"`
+}
+
+// MarshalJSON leaves out the unset dates, omitempty has no effect on time.Time
+func (c Constraint) MarshalJSON() ([]byte, error) {
+	type constraint Constraint
+	v := struct {
+		constraint
+		DistrustTLSAfter   *time.Time `json:"distrust_tls_after,omitempty"`
+		DistrustEmailAfter *time.Time `json:"distrust_email_after,omitempty"`
+	}{constraint: constraint(c)}
+	if !c.DistrustTLSAfter.IsZero() {
+		v.DistrustTLSAfter = &c.DistrustTLSAfter
+	}
+	if !c.DistrustEmailAfter.IsZero() {
+		v.DistrustEmailAfter = &c.DistrustEmailAfter
+	}
+	return json.Marshal(v)
+}
+
+func (c *Constraint) distrustsAfter() bool {
+	return !c.DistrustTLSAfter.IsZero() || !c.DistrustEmailAfter.IsZero()
+}
+
+func (c *Constraint) String() string {
+	parts := []string{}
+	if !c.DistrustTLSAfter.IsZero() {
+		parts = append(parts, "distrusted for TLS certificates issued after "+c.DistrustTLSAfter.Format("2006-01-02"))
+	}
+	if !c.DistrustEmailAfter.IsZero() {
+		parts = append(parts, "distrusted for S/MIME certificates issued after "+c.DistrustEmailAfter.Format("2006-01-02"))
+	}
+	return strings.Join(parts, "; ")
+}
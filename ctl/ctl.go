@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/x509/pkix"
 	"fmt"
+	"sort"
 	"text/template"
 	"time"
 
@@ -11,9 +12,13 @@ import (
 )
 
 const (
+	APPLE       = "apple"
+	CHROME      = "chrome"
 	MICROSOFT   = "microsoft"
+	MOZILLA     = "mozilla"
 	MOZILLA_NSS = "mozilla_nss"
 	OPENJDK     = "openjdk"
+	INTERNAL    = "internal"
 )
 
 type CTL struct {
@@ -22,18 +27,29 @@ type CTL struct {
 	Removed   Entrys    `yaml:"removed,omitempty"`
 }
 
-// Entrys maps from sum256(cert.Raw) to subject name.
-type Entrys map[string]string
-
 type VerifyResult struct {
-	Total        int
-	TrustedCerts []*Cert `json:"_"`
+	Vendor       string  `json:"vendor"`
+	Total        int     `json:"total"`
+	TrustedCerts []*Cert `json:"trusted_certs,omitempty"`
+	PartialCerts []*Cert `json:"partial_certs,omitempty"`
+	partialDesc  string
 	AllowedCerts []*Cert `json:"allowed_certs,omitempty"`
 	allowedDesc  string
 	RemovedCerts []*Cert `json:"removed_certs,omitempty"`
 	removedDesc  string
 	UnknownCerts []*Cert `json:"unknown_certs,omitempty"`
 	unknownDesc  string
+	// MissingEntries are the roots trusted by the vendor for websites that
+	// are not among the verified certificates
+	MissingEntries Entrys `json:"missing_entries,omitempty"`
+	missingDesc    string
+	// ExpiryWindow is the time before their expiry in which the reports list
+	// certificates as expiring, DefaultExpiryWindow unless changed
+	ExpiryWindow time.Duration `json:"-"`
+	// entries maps the checksum of matched certificates to the vendor's CTL entry
+	entries Entrys
+	// details explain the status of certificates, e.g. why they were removed
+	details map[string][]detail
 }
 
 func NewCTL() *CTL {
@@ -43,52 +59,237 @@ func NewCTL() *CTL {
 	}
 }
 
+// legacy reports whether the CTL has entries saved by an older version, which
+// only wrote their names. Such a CTL is parsed again even if its sources have
+// not changed since, to fill in the usages, constraints and sources.
+func (ctl *CTL) legacy() bool {
+	if ctl == nil {
+		return false
+	}
+	for _, entrys := range []Entrys{ctl.Trusted, ctl.Removed} {
+		for _, e := range entrys {
+			if e.Source == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VerifyAs sorts certs by their status in the CTL of vendor, for CTLSource
+// implementations outside of this package. The details of partially
+// distrusted and removed certificates are taken from their entries.
+func (ctl *CTL) VerifyAs(vendor string, certs []*Cert, allowedCerts Entrys) *VerifyResult {
+	ret := VerifyResult{
+		Vendor:       vendor,
+		Total:        len(certs),
+		TrustedCerts: []*Cert{},
+		AllowedCerts: []*Cert{},
+		allowedDesc:  "Allow by yourself in the config file.\n",
+		RemovedCerts: []*Cert{},
+		removedDesc:  fmt.Sprintf("Removed from the %s CTL, see the status and reason of each certificate.\n", vendor),
+		UnknownCerts: []*Cert{},
+	}
+	ctl.verify(certs, allowedCerts, &ret)
+	return &ret
+}
+
 // verify that the specified certificate is included in the CTL or has been removed
 func (ctl *CTL) verify(certs []*Cert, allowedCerts Entrys, ret *VerifyResult) {
+	if ret.entries == nil {
+		ret.entries = Entrys{}
+	}
+	if ret.details == nil {
+		ret.details = map[string][]detail{}
+	}
+	if ret.partialDesc == "" {
+		ret.partialDesc = fmt.Sprintf("Included in the %s CTL, but not trusted for TLS server certificates or only until a date.\n", ret.Vendor)
+	}
+	ret.ExpiryWindow = DefaultExpiryWindow
+	if ret.missingDesc == "" {
+		ret.missingDesc = fmt.Sprintf("Trusted by %s, but not found, e.g. added after the ca-certificates package was built.\n", ret.Vendor)
+	}
+	found := map[string]bool{}
 	for _, cert := range certs {
-		_, ok := ctl.Trusted[cert.Checksum]
+		found[cert.Checksum] = true
+		entry, ok := ctl.Trusted[cert.Checksum]
 		if ok {
-			ret.TrustedCerts = append(ret.TrustedCerts, cert)
+			if entry.PartiallyDistrusted() {
+				ret.PartialCerts = append(ret.PartialCerts, cert)
+				ret.details[cert.Checksum] = entry.distrustDetails()
+			} else {
+				ret.TrustedCerts = append(ret.TrustedCerts, cert)
+			}
+			ret.entries[cert.Checksum] = entry
 		} else {
-			_, ok := allowedCerts[cert.Checksum]
+			entry, ok := allowedCerts[cert.Checksum]
 			if ok {
 				ret.AllowedCerts = append(ret.AllowedCerts, cert)
+				ret.entries[cert.Checksum] = entry
 			} else {
-				_, ok = ctl.Removed[cert.Checksum]
+				entry, ok = ctl.Removed[cert.Checksum]
 				if ok {
 					ret.RemovedCerts = append(ret.RemovedCerts, cert)
+					ret.entries[cert.Checksum] = entry
+					ret.details[cert.Checksum] = entry.removalDetails()
 				} else {
 					ret.UnknownCerts = append(ret.UnknownCerts, cert)
 				}
 			}
 		}
 	}
+	ret.MissingEntries = Entrys{}
+	for k, entry := range ctl.Trusted {
+		if !found[k] && entry.Trusts(TrustWebsites) {
+			ret.MissingEntries[k] = entry
+		}
+	}
+}
+
+// Missing returns the checksums of the missing roots sorted by their name.
+func (result *VerifyResult) Missing() []string {
+	ret := make([]string, 0, len(result.MissingEntries))
+	for k := range result.MissingEntries {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		ni, nj := result.MissingEntries[ret[i]].Name, result.MissingEntries[ret[j]].Name
+		if ni != nj {
+			return ni < nj
+		}
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+// DefaultExpiryWindow is the ExpiryWindow of new results
+const DefaultExpiryWindow = 90 * 24 * time.Hour
+
+// Expiry classes of a certificate, see VerifyResult.expiry
+const (
+	expiryExpired     = "expired"
+	expiryExpiring    = "expiring"
+	expiryNotYetValid = "not_yet_valid"
+)
+
+// Expired returns the certificates of all buckets that are expired at t.
+func (result *VerifyResult) Expired(t time.Time) []*Cert {
+	return result.filter(func(cert *Cert) bool {
+		return cert.NotAfter.Before(t)
+	})
+}
+
+// Expiring returns the certificates of all buckets that are not expired at t,
+// but expire within window after t.
+func (result *VerifyResult) Expiring(t time.Time, window time.Duration) []*Cert {
+	return result.filter(func(cert *Cert) bool {
+		return !cert.NotAfter.Before(t) && cert.NotAfter.Before(t.Add(window))
+	})
+}
+
+// NotYetValid returns the certificates of all buckets that are only valid
+// after t.
+func (result *VerifyResult) NotYetValid(t time.Time) []*Cert {
+	return result.filter(func(cert *Cert) bool {
+		return cert.NotBefore.After(t)
+	})
+}
+
+// expiry returns the expiry class of cert at now, or an empty string if it is
+// valid beyond the ExpiryWindow
+func (result *VerifyResult) expiry(cert *Cert, now time.Time) string {
+	switch {
+	case cert.NotBefore.After(now):
+		return expiryNotYetValid
+	case cert.NotAfter.Before(now):
+		return expiryExpired
+	case cert.NotAfter.Before(now.Add(result.ExpiryWindow)):
+		return expiryExpiring
+	}
+	return ""
+}
+
+func (result *VerifyResult) filter(keep func(*Cert) bool) []*Cert {
+	ret := []*Cert{}
+	for _, certs := range [][]*Cert{result.TrustedCerts, result.PartialCerts, result.AllowedCerts, result.RemovedCerts, result.UnknownCerts} {
+		for _, cert := range certs {
+			if keep(cert) {
+				ret = append(ret, cert)
+			}
+		}
+	}
+	return ret
+}
+
+// expiryDetails prepend the class of the certificate to its details, for
+// the expiry sections that list certificates of all buckets
+func (result *VerifyResult) expiryDetails(cert *Cert) []detail {
+	return append([]detail{{Label: "Class", Value: result.Status(cert.Checksum)}}, result.certDetails(cert)...)
 }
 
 func (result *VerifyResult) ConsoleReport() (output string) {
 	var (
 		countTrusted = len(result.TrustedCerts)
+		countPartial = len(result.PartialCerts)
 		countRemoved = len(result.RemovedCerts)
 		countAllowed = len(result.AllowedCerts)
 		countUnknown = len(result.UnknownCerts)
+		countMissing = len(result.MissingEntries)
+		now          = time.Now()
+		expired      = result.Expired(now)
+		expiring     = result.Expiring(now, result.ExpiryWindow)
+		notYetValid  = result.NotYetValid(now)
 	)
 	table, err := pterm.DefaultTable.WithHasHeader().WithRightAlignment().WithData(
 		pterm.TableData{
-			{"Total", "Trust", "Allow", "Removal", "Unknown"},
-			{fmt.Sprint(result.Total), fmt.Sprint(countTrusted), fmt.Sprint(countAllowed), fmt.Sprint(countRemoved), fmt.Sprint(countUnknown)},
+			{"Total", "Trust", "Partial", "Allow", "Removal", "Unknown", "Missing", "Expired", "Expiring", "Not Yet Valid"},
+			{fmt.Sprint(result.Total), fmt.Sprint(countTrusted), fmt.Sprint(countPartial), fmt.Sprint(countAllowed), fmt.Sprint(countRemoved), fmt.Sprint(countUnknown), fmt.Sprint(countMissing), fmt.Sprint(len(expired)), fmt.Sprint(len(expiring)), fmt.Sprint(len(notYetValid))},
 		}).Srender()
 	if err != nil {
 		output += pterm.Error.Sprintf("%v", err)
 		return
 	}
 	output += table + "\n"
-	output += formatCerts("Allowed Certificates", result.allowedDesc, result.AllowedCerts)
-	output += formatCerts("Removed Certificates", result.removedDesc, result.RemovedCerts)
-	output += formatCerts("Unknown Certificates", result.unknownDesc, result.UnknownCerts)
+	output += result.formatCerts("Partially Distrusted Certificates", result.partialDesc, result.PartialCerts, result.certDetails)
+	output += result.formatCerts("Allowed Certificates", result.allowedDesc, result.AllowedCerts, result.certDetails)
+	output += result.formatCerts("Removed Certificates", result.removedDesc, result.RemovedCerts, result.certDetails)
+	output += result.formatCerts("Unknown Certificates", result.unknownDesc, result.UnknownCerts, result.certDetails)
+	output += result.formatMissing()
+	output += result.formatCerts("Expired Certificates", "", expired, result.expiryDetails)
+	output += result.formatCerts(fmt.Sprintf("Certificates Expiring within %d Days", result.ExpiryWindow/(24*time.Hour)),
+		"Schedule the rotation of these roots before they expire.\n", expiring, result.expiryDetails)
+	output += result.formatCerts("Not Yet Valid Certificates",
+		"Not valid before a future date, check the certificate and the clock of this system.\n", notYetValid, result.expiryDetails)
+	return
+}
+
+// formatMissing lists the missing roots, which have no certificate to print
+func (result *VerifyResult) formatMissing() (output string) {
+	if len(result.MissingEntries) < 1 {
+		return
+	}
+	output += pterm.DefaultSection.WithLevel(3).Sprintf("%s:%4d", "Missing Certificates", len(result.MissingEntries))
+	if result.missingDesc != "" {
+		output += pterm.ThemeDefault.InfoMessageStyle.Sprintln(result.missingDesc)
+	}
+	for _, k := range result.Missing() {
+		output += fmt.Sprintf("SHA256:\t%s\n  Name:       %s\n", k, result.MissingEntries[k].Name)
+	}
 	return
 }
 
-func formatCerts(title, desc string, certs []*Cert) (output string) {
+// detail is a labeled line printed below a certificate
+type detail struct {
+	Label string
+	Value string
+}
+
+// certDetails returns the details of the certificate to print below it, if any
+func (result *VerifyResult) certDetails(cert *Cert) []detail {
+	return result.details[cert.Checksum]
+}
+
+func (result *VerifyResult) formatCerts(title, desc string, certs []*Cert, details func(*Cert) []detail) (output string) {
 	if len(certs) < 1 {
 		return
 	}
@@ -98,32 +299,34 @@ func formatCerts(title, desc string, certs []*Cert) (output string) {
 	}
 
 	tpl := template.Must(template.New("").Funcs(template.FuncMap{
-		"redIfNotExpired": func(t time.Time) string {
+		"colorNotBefore": func(t time.Time) string {
 			txt := t.Format("2006-01-02T15:04:05Z")
 			if t.After(time.Now()) {
 				txt = pterm.Red(txt)
 			}
 			return txt
 		},
-		"pkixName": func(n pkix.Name) string {
-			if len(n.CommonName) > 0 {
-				return n.CommonName
-			}
-			if len(n.OrganizationalUnit) > 0 {
-				return n.OrganizationalUnit[0]
-			}
-			if len(n.Organization) > 0 {
-				return n.Organization[0]
+		"colorExpiry": func(t time.Time) string {
+			txt := t.Format("2006-01-02T15:04:05Z")
+			switch now := time.Now(); {
+			case t.Before(now):
+				txt = pterm.Red(txt)
+			case t.Before(now.Add(result.ExpiryWindow)):
+				txt = pterm.Yellow(txt)
 			}
-			return n.String()
+			return txt
 		},
+		"pkixName": pkixName,
+		"details":  details,
 	}).Parse(`
 {{- range . -}}
 SHA256:	{{ .Checksum }}
   Subject:    {{ .Subject | pkixName }}
   Issuer:     {{ .Issuer | pkixName }}
-  Valid from: {{ .NotBefore.Format "2006-01-02T15:04:05Z" }}
-          to: {{ .NotAfter | redIfNotExpired }}
+  Valid from: {{ .NotBefore | colorNotBefore }}
+          to: {{ .NotAfter | colorExpiry }}
+{{ range details . }}  {{ printf "%-11s" (print .Label ":") }} {{ .Value }}
+{{ end -}}
 {{ end -}}
 	`))
 
@@ -135,3 +338,17 @@ SHA256:	{{ .Checksum }}
 	output += buf.String()
 	return
 }
+
+// pkixName returns a short display name of n
+func pkixName(n pkix.Name) string {
+	if len(n.CommonName) > 0 {
+		return n.CommonName
+	}
+	if len(n.OrganizationalUnit) > 0 {
+		return n.OrganizationalUnit[0]
+	}
+	if len(n.Organization) > 0 {
+		return n.Organization[0]
+	}
+	return n.String()
+}
@@ -1,7 +1,10 @@
 package ctl
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -25,9 +28,19 @@ func NewAppleCTL() *AppleCTL {
 	}
 }
 
+// Name of the source, see CTLSource
+func (ctl *AppleCTL) Name() string { return APPLE }
+
+// Vendor name shown in reports, see CTLSource
+func (ctl *AppleCTL) Vendor() string { return "Apple" }
+
+// TrustList returns the fetched CTL, see CTLSource
+func (ctl *AppleCTL) TrustList() *CTL { return ctl.CTL }
+
 // Verify that the specified certificate is included in the CTL or has been removed
 func (ctl *AppleCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
 	ret := VerifyResult{
+		Vendor:       ctl.Vendor(),
 		Total:        len(certs),
 		TrustedCerts: []*Cert{},
 		AllowedCerts: []*Cert{},
@@ -41,8 +54,8 @@ func (ctl *AppleCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
 	return &ret
 }
 
-func (ctl *AppleCTL) Fetch() error {
-	doc, err := htmlquery.LoadURL(AppleKBURL)
+func (ctl *AppleCTL) Fetch(ctx context.Context, cl *http.Client) error {
+	doc, err := loadHTML(ctx, cl, ctl.PublishURL)
 	if err != nil {
 		return err
 	}
@@ -55,7 +68,7 @@ func (ctl *AppleCTL) Fetch() error {
 	if err != nil {
 		return fmt.Errorf("can not parse apple publish date: %w", err)
 	}
-	if strings.Compare(date, ctl.PublishedDate) < 1 {
+	if !ctl.legacy() && strings.Compare(date, ctl.PublishedDate) < 1 {
 		return nil // no update
 	}
 	ctl.PublishedDate = date
@@ -63,41 +76,59 @@ func (ctl *AppleCTL) Fetch() error {
 	if nodeLink == nil {
 		return fmt.Errorf("can not find apple publish link")
 	}
-	link := htmlquery.SelectAttr(nodeLink, "href") // link to latest url
-	return ctl.fetchData(link)
+	link, err := resolveURL(ctl.PublishURL, htmlquery.SelectAttr(nodeLink, "href")) // link to latest url
+	if err != nil {
+		return fmt.Errorf("can not parse apple publish link: %w", err)
+	}
+	return ctl.fetchData(ctx, cl, link)
 }
 
-func (ctl *AppleCTL) fetchData(link string) error {
-	page, err := htmlquery.LoadURL(link)
+func (ctl *AppleCTL) fetchData(ctx context.Context, cl *http.Client, link string) error {
+	page, err := loadHTML(ctx, cl, link)
 	if err != nil {
 		return err
 	}
 	xpathTrusted := "//h2[@id='trusted' or text()='Trusted Certificates' or text()='Trusted certificates']/following-sibling::div[1]//table"
 	rows := parseTable(page, fmt.Sprintf("%s//th", xpathTrusted), fmt.Sprintf("%s//tr[position()>1]", xpathTrusted))
-	ctl.Trusted = extractEntrys(rows)
+	ctl.Trusted = extractEntrys(rows, Entry{Source: SourceAppleTable, URL: link})
 	if len(ctl.Trusted) == 0 {
 		return fmt.Errorf("can not find data table in the page")
 	}
 	xpathBlocked := "//h2[@id='blocked' or text()='Blocked Certificates' or text()='Blocked certificates']/following-sibling::div[1]//table"
 	rows = parseTable(page, fmt.Sprintf("%s//th", xpathBlocked), fmt.Sprintf("%s//tr[position()>1]", xpathBlocked))
-	ctl.Removed = extractEntrys(rows)
+	ctl.Removed = extractEntrys(rows, Entry{Status: "Blocked", Source: SourceAppleTable, URL: link})
 	ctl.UpdatedAt = time.Now()
 	return nil
 }
 
-func extractEntrys(rows []map[string]string) Entrys {
+// extractEntrys reads the rows of a trust store table into entries based on tpl
+func extractEntrys(rows []map[string]string, tpl Entry) Entrys {
 	entrys := Entrys{}
 	fpKey := strings.ToUpper("Fingerprint (SHA-256)")
 	certNameKey := strings.ToUpper("Certificate name")
+	keyTypeKey := strings.ToUpper("Type")
+	keySizeKey := strings.ToUpper("Key size")
 	for _, v := range rows {
 		fingerprint := strings.ToUpper(strings.ReplaceAll(v[fpKey], " ", ""))
 		if fingerprint != "" {
-			entrys[fingerprint] = v[certNameKey]
+			entry := tpl
+			entry.Name = v[certNameKey]
+			entry.KeyType = strings.TrimSpace(v[keyTypeKey] + " " + v[keySizeKey])
+			entrys[fingerprint] = entry
 		}
 	}
 	return entrys
 }
 
+// loadHTML fetches and parses the page at url, which may be a file:// URL
+func loadHTML(ctx context.Context, cl *http.Client, url string) (*html.Node, error) {
+	body, err := getBody(ctx, cl, url)
+	if err != nil {
+		return nil, err
+	}
+	return htmlquery.Parse(bytes.NewReader(body))
+}
+
 func parseTable(top *html.Node, thExpr, trExpr string) []map[string]string {
 	data := []map[string]string{}
 	thNodes := htmlquery.Find(top, thExpr)
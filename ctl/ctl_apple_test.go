@@ -1,10 +1,13 @@
 package ctl
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestAppleCTL_Fetch(t *testing.T) {
 	ctl := NewAppleCTL()
-	err := ctl.Fetch()
+	err := ctl.Fetch(context.Background(), nil)
 	if err != nil {
 		t.Errorf("AppleCTL.Fetch() error = %v", err)
 	}
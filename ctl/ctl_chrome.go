@@ -0,0 +1,213 @@
+package ctl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// root_store.textproto of the Chrome Root Store, base64 encoded by gitiles
+	ChromeRootStoreURL = "https://chromium.googlesource.com/chromium/src/+/main/net/data/ssl/chrome_root_store/root_store.textproto?format=TEXT"
+	// All certificate records in CCADB, the Chrome Status column tells the included and removed roots
+	ChromeCCADBReportCSV = "https://ccadb-public.secure.force.com/ccadb/AllCertificateRecordsCSVFormat"
+)
+
+type ChromeCTL struct {
+	*CTL              `yaml:",inline"`
+	URLRootStore      string `yaml:"url_root_store,omitempty"`
+	ChecksumRootStore string `yaml:"checksum_root_store,omitempty"`
+	URLReport         string `yaml:"url_report,omitempty"`
+	ChecksumReport    string `yaml:"checksum_report,omitempty"`
+}
+
+func NewChromeCTL() *ChromeCTL {
+	return &ChromeCTL{
+		CTL:               NewCTL(),
+		URLRootStore:      ChromeRootStoreURL,
+		ChecksumRootStore: "",
+		URLReport:         ChromeCCADBReportCSV,
+		ChecksumReport:    "",
+	}
+}
+
+// Name of the source, see CTLSource
+func (ctl *ChromeCTL) Name() string { return CHROME }
+
+// Vendor name shown in reports, see CTLSource
+func (ctl *ChromeCTL) Vendor() string { return "Chrome" }
+
+// TrustList returns the fetched CTL, see CTLSource
+func (ctl *ChromeCTL) TrustList() *CTL { return ctl.CTL }
+
+// Verify that the specified certificate is included in the CTL or has been removed
+func (ctl *ChromeCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
+	ret := VerifyResult{
+		Vendor:       ctl.Vendor(),
+		Total:        len(certs),
+		TrustedCerts: []*Cert{},
+		AllowedCerts: []*Cert{},
+		allowedDesc:  "Allow by yourself in the config file.\n",
+		RemovedCerts: []*Cert{},
+		removedDesc:  "Use SHA256 to find the reason for removal in the Chrome Root Store history: \nhttps://chromium.googlesource.com/chromium/src/+log/main/net/data/ssl/chrome_root_store/root_store.textproto\n",
+		UnknownCerts: []*Cert{},
+		unknownDesc:  "",
+	}
+	ctl.verify(certs, allowedCerts, &ret)
+	return &ret
+}
+
+// Fetch the Chrome Root Store and Chrome's CA certificate records from https://www.ccadb.org
+func (ctl *ChromeCTL) Fetch(ctx context.Context, cl *http.Client) error {
+	if ctl.CTL == nil {
+		ctl.CTL = NewCTL()
+	}
+	if ctl.legacy() {
+		ctl.ChecksumRootStore, ctl.ChecksumReport = "", ""
+	}
+
+	var rootStore, report []byte
+	err := fetchConcurrently(
+		func() (err error) { rootStore, err = getBody(ctx, cl, ctl.URLRootStore); return err },
+		func() (err error) { report, err = getBody(ctx, cl, ctl.URLReport); return err },
+	)
+	if err != nil {
+		return err
+	}
+	if err := ctl.parseRootStore(rootStore); err != nil {
+		return err
+	}
+
+	return ctl.parseReportCSV(report)
+}
+
+// parseRootStore replaces the trusted entries with the trust anchors of
+// root_store.textproto, named by the comment preceding each anchor. Anchors
+// that are no longer included are moved to Removed without a removal date,
+// the snapshot may be the first one fetched after it. The SCT constraints of
+// anchors distrusted for certificates issued after a date are kept.
+func (ctl *ChromeCTL) parseRootStore(body []byte) error {
+	checksum := getChecksum(body)
+	if checksum == ctl.ChecksumRootStore { // no update
+		return nil
+	}
+	if !bytes.Contains(body, []byte("trust_anchors")) {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+		if err != nil {
+			return fmt.Errorf("read root store err: %w", err)
+		}
+		body = decoded
+	}
+
+	trusted := Entrys{}
+	var comment, name, sha256 string
+	var sctNotAfter time.Time
+	anchor, depth := false, 0
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasSuffix(line, "{"):
+			if depth == 0 {
+				anchor = strings.HasPrefix(line, "trust_anchors")
+				name, sha256, sctNotAfter = comment, "", time.Time{}
+			}
+			depth++
+		case line == "}":
+			depth--
+			if depth == 0 && anchor && sha256 != "" {
+				entry := Entry{Name: name, Source: SourceRootStore}
+				if !sctNotAfter.IsZero() {
+					entry.Constraint = &Constraint{DistrustTLSAfter: sctNotAfter}
+				}
+				trusted[sha256] = entry
+			}
+		case depth == 0:
+			if strings.HasPrefix(line, "#") {
+				comment = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			} else {
+				comment = ""
+			}
+		case depth == 1 && anchor && strings.HasPrefix(line, "sha256_hex:"):
+			sha256 = strings.ToUpper(strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "sha256_hex:")), `"`))
+		case depth == 2 && anchor && strings.HasPrefix(line, "sct_not_after_sec:"):
+			sec, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "sct_not_after_sec:")), 10, 64)
+			if err != nil {
+				return fmt.Errorf("read root store err: %w", err)
+			}
+			sctNotAfter = time.Unix(sec, 0).UTC()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read root store err: %w", err)
+	}
+	if len(trusted) == 0 {
+		return fmt.Errorf("no trust anchors found in the root store")
+	}
+
+	if ctl.Removed == nil {
+		ctl.Removed = Entrys{}
+	}
+	for k, v := range ctl.Trusted {
+		if _, ok := trusted[k]; !ok {
+			ctl.Removed[k] = Entry{
+				Name:   v.Name,
+				Status: "Removed",
+				Reason: "no longer a trust anchor in the Chrome Root Store",
+				Source: SourceRootStore,
+			}
+		}
+	}
+	for k := range trusted {
+		delete(ctl.Removed, k)
+	}
+	ctl.Trusted = trusted
+	ctl.ChecksumRootStore = checksum
+	ctl.ChecksumReport = "" // name the new entries from the report again
+	ctl.UpdatedAt = time.Now()
+	return nil
+}
+
+// parseReportCSV names the trusted entries and collects the roots removed from Chrome
+func (ctl *ChromeCTL) parseReportCSV(body []byte) error {
+	checksum := getChecksum(body)
+	if checksum == ctl.ChecksumReport { // no update
+		return nil
+	}
+
+	c, err := csvReadToMap(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("read csv file err: %w", err)
+	}
+
+	if ctl.Removed == nil {
+		ctl.Removed = Entrys{}
+	}
+	for _, v := range c {
+		if v["Certificate Record Type"] != "Root Certificate" {
+			continue
+		}
+		name := v["Certificate Name"]
+		sha256 := v["SHA-256 Fingerprint"]
+		if entry, ok := ctl.Trusted[sha256]; ok {
+			if name != "" {
+				entry.Name = name
+				ctl.Trusted[sha256] = entry
+			}
+			continue
+		}
+		if status := v["Chrome Status"]; status == "Removed" {
+			ctl.Removed[sha256] = Entry{Name: name, Status: status, Source: SourceCCADB}
+		}
+	}
+	ctl.ChecksumReport = checksum
+	ctl.UpdatedAt = time.Now()
+
+	return nil
+}
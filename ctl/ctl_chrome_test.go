@@ -0,0 +1,86 @@
+package ctl
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const testRootStore = `# proto-file: chrome_root_store.proto
+# proto-message: RootStore
+
+version_major: 25
+
+# Kept Root
+trust_anchors {
+  sha256_hex: "aa00000000000000000000000000000000000000000000000000000000000000"
+  constraints {
+    sct_not_after_sec: 1730419200
+  }
+}
+
+# Dropped Root
+trust_anchors {
+  sha256_hex: "bb00000000000000000000000000000000000000000000000000000000000000"
+}
+
+additional_certs {
+  sha256_hex: "cc00000000000000000000000000000000000000000000000000000000000000"
+}
+`
+
+const testChromeReport = `"CA Owner","Certificate Name","Certificate Record Type","SHA-256 Fingerprint","Chrome Status"
+"Test CA","Kept Root CA","Root Certificate","AA00000000000000000000000000000000000000000000000000000000000000","Included"
+"Test CA","Old Root CA","Root Certificate","DD00000000000000000000000000000000000000000000000000000000000000","Removed"
+"Test CA","Never Root CA","Root Certificate","EE00000000000000000000000000000000000000000000000000000000000000","Not Included"
+"Test CA","Removed Intermediate","Intermediate Certificate","FF00000000000000000000000000000000000000000000000000000000000000","Removed"
+`
+
+func TestChromeCTL_parse(t *testing.T) {
+	ctl := NewChromeCTL()
+	if err := ctl.parseRootStore([]byte(base64.StdEncoding.EncodeToString([]byte(testRootStore)))); err != nil {
+		t.Fatalf("ChromeCTL.parseRootStore() error = %v", err)
+	}
+	want := Entrys{
+		"AA00000000000000000000000000000000000000000000000000000000000000": {Name: "Kept Root"},
+		"BB00000000000000000000000000000000000000000000000000000000000000": {Name: "Dropped Root"},
+	}
+	if len(ctl.Trusted) != len(want) {
+		t.Fatalf("ChromeCTL.parseRootStore() trusted = %v, want %v", ctl.Trusted, want)
+	}
+	for k, v := range want {
+		if ctl.Trusted[k].Name != v.Name {
+			t.Errorf("ChromeCTL.parseRootStore() trusted[%s] = %q, want %q", k[:8], ctl.Trusted[k].Name, v.Name)
+		}
+	}
+
+	if c := ctl.Trusted["AA00000000000000000000000000000000000000000000000000000000000000"].Constraint; c == nil || c.DistrustTLSAfter.Unix() != 1730419200 {
+		t.Errorf("ChromeCTL.parseRootStore() constraint = %+v, want distrust for TLS after 1730419200", c)
+	}
+
+	if err := ctl.parseReportCSV([]byte(testChromeReport)); err != nil {
+		t.Fatalf("ChromeCTL.parseReportCSV() error = %v", err)
+	}
+	if name := ctl.Trusted["AA00000000000000000000000000000000000000000000000000000000000000"].Name; name != "Kept Root CA" {
+		t.Errorf("ChromeCTL.parseReportCSV() trusted name = %q, want %q", name, "Kept Root CA")
+	}
+	if len(ctl.Removed) != 1 || ctl.Removed["DD00000000000000000000000000000000000000000000000000000000000000"].Name != "Old Root CA" {
+		t.Errorf("ChromeCTL.parseReportCSV() removed = %v, want only Old Root CA", ctl.Removed)
+	}
+
+	// next snapshot: the dropped root is no longer a trust anchor
+	next := testRootStore[:strings.Index(testRootStore, "# Dropped Root")]
+	if err := ctl.parseRootStore([]byte(next)); err != nil {
+		t.Fatalf("ChromeCTL.parseRootStore() error = %v", err)
+	}
+	if _, ok := ctl.Removed["BB00000000000000000000000000000000000000000000000000000000000000"]; !ok || len(ctl.Trusted) != 1 {
+		t.Errorf("ChromeCTL.parseRootStore() trusted = %v, removed = %v, want Dropped Root removed", ctl.Trusted, ctl.Removed)
+	}
+	dropped := ctl.Removed["BB00000000000000000000000000000000000000000000000000000000000000"]
+	if dropped.Name != "Dropped Root" || dropped.Status != "Removed" || dropped.Source != SourceRootStore {
+		t.Errorf("ChromeCTL.parseRootStore() dropped = %+v, want Dropped Root removed from the root store", dropped)
+	}
+	if !dropped.RemovedAt.IsZero() {
+		t.Errorf("ChromeCTL.parseRootStore() dropped at %v, want no removal date", dropped.RemovedAt)
+	}
+}
@@ -0,0 +1,170 @@
+package ctl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InternalLabel is the vendor name of an internal CTL without a label
+const InternalLabel = "Internal"
+
+// InternalCTL is an internal trust list, e.g. of a corporate PKI, read from
+// Path: a YAML file of entries, or a CA bundle file or directory of PEM or
+// DER encoded certificates.
+//
+// The YAML file has the format of the CTLs in ctlcheck.yml, with an optional
+// label and the owner and expiry date of each root:
+//
+//	label: Corp PKI
+//	trusted:
+//	  <SHA-256>:
+//	    name: Corp Root CA 2020
+//	    owner: pki-team@corp.example
+//	    expires: 2030-01-01
+//	removed:
+//	  <SHA-256>:
+//	    name: Corp Root CA 2010
+//	    reason: replaced by Corp Root CA 2020
+type InternalCTL struct {
+	*CTL `yaml:",inline"`
+	// Label is the name of the trust list shown in reports, e.g. "Corp PKI",
+	// the label in the YAML file takes precedence
+	Label string `yaml:"label,omitempty"`
+	Path  string `yaml:"path,omitempty"`
+}
+
+func NewInternalCTL() *InternalCTL {
+	return &InternalCTL{
+		CTL:   NewCTL(),
+		Label: "",
+		Path:  "",
+	}
+}
+
+// Name of the source, see CTLSource
+func (ctl *InternalCTL) Name() string { return INTERNAL }
+
+// Vendor name shown in reports, see CTLSource
+func (ctl *InternalCTL) Vendor() string {
+	if ctl.Label != "" {
+		return ctl.Label
+	}
+	return InternalLabel
+}
+
+// TrustList returns the fetched CTL, see CTLSource
+func (ctl *InternalCTL) TrustList() *CTL { return ctl.CTL }
+
+// Verify that the specified certificate is included in the CTL or has been
+// removed. Roots whose trust expired are reported as removed.
+func (ctl *InternalCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
+	ret := ctl.at(time.Now()).VerifyAs(ctl.Vendor(), certs, allowedCerts)
+	ret.removedDesc = fmt.Sprintf("No longer trusted by %s, ask the owner of the root for its replacement.\n", ctl.Vendor())
+	ret.unknownDesc = fmt.Sprintf("Not in %s, check that the root is expected on this system.\n", ctl.Vendor())
+	return ret
+}
+
+// at returns the CTL at now, with the trusted entries whose trust expired
+// moved to the removed ones
+func (ctl *InternalCTL) at(now time.Time) *CTL {
+	ret := &CTL{UpdatedAt: ctl.UpdatedAt, Trusted: Entrys{}, Removed: Entrys{}}
+	for k, v := range ctl.Removed {
+		ret.Removed[k] = v
+	}
+	for k, v := range ctl.Trusted {
+		if !v.Expires.IsZero() && !now.Before(v.Expires) {
+			v.Status = "Expired"
+			v.RemovedAt = v.Expires
+			ret.Removed[k] = v
+			continue
+		}
+		ret.Trusted[k] = v
+	}
+	return ret
+}
+
+// Fetch reads the internal CTL from Path, which is local, so ctx and cl are
+// not used
+func (ctl *InternalCTL) Fetch(ctx context.Context, cl *http.Client) error {
+	if ctl.CTL == nil {
+		ctl.CTL = NewCTL()
+	}
+	if ctl.Path == "" {
+		return fmt.Errorf("no internal CTL file or directory given")
+	}
+	fi, err := os.Stat(ctl.Path)
+	if err != nil {
+		return err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(ctl.Path)); {
+	case !fi.IsDir() && (ext == ".yml" || ext == ".yaml"):
+		err = ctl.loadYAML(ctl.Path)
+	default:
+		err = ctl.loadCerts(ctl.Path, fi.IsDir())
+	}
+	if err != nil {
+		return fmt.Errorf("load internal CTL %s: %w", ctl.Path, err)
+	}
+	ctl.UpdatedAt = time.Now()
+	return nil
+}
+
+func (ctl *InternalCTL) loadYAML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Label   string `yaml:"label"`
+		Trusted Entrys `yaml:"trusted"`
+		Removed Entrys `yaml:"removed"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	if len(file.Trusted) == 0 {
+		return fmt.Errorf("no trusted roots")
+	}
+	if file.Label != "" {
+		ctl.Label = file.Label
+	}
+	ctl.Trusted, ctl.Removed = Entrys{}, Entrys{}
+	for k, v := range file.Trusted {
+		v.Source = SourceInternal
+		ctl.Trusted[strings.ToUpper(k)] = v
+	}
+	for k, v := range file.Removed {
+		v.Source = SourceInternal
+		ctl.Removed[strings.ToUpper(k)] = v
+	}
+	return nil
+}
+
+func (ctl *InternalCTL) loadCerts(path string, dir bool) error {
+	store := NewCertStore()
+	var err error
+	if dir {
+		err = store.LoadBundleDir(path)
+	} else {
+		err = store.LoadBundleFiles(path)
+	}
+	if err != nil {
+		return err
+	}
+	if len(store.Certs) == 0 {
+		return fmt.Errorf("no certificates found")
+	}
+	ctl.Trusted, ctl.Removed = Entrys{}, Entrys{}
+	for _, cert := range store.Certs {
+		ctl.Trusted[cert.Checksum] = Entry{Name: pkixName(cert.Subject), Source: SourceInternal}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package ctl
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInternalCTL_Fetch(t *testing.T) {
+	current := newValidCert(t, "Corp Root CA 2020")
+	expired := newValidCert(t, "Corp Root CA 2015")
+	replaced := newValidCert(t, "Corp Root CA 2010")
+	other := newValidCert(t, "Other Root")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "corp.yml")
+	data := fmt.Sprintf(`label: Corp PKI
+trusted:
+  %s:
+    name: Corp Root CA 2020
+    owner: pki-team@corp.example
+    expires: 2999-01-01
+  %s:
+    name: Corp Root CA 2015
+    owner: pki-team@corp.example
+    expires: 2020-01-01
+removed:
+  %s:
+    name: Corp Root CA 2010
+    reason: replaced by Corp Root CA 2020
+`, current.Checksum, expired.Checksum, replaced.Checksum)
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctl := NewInternalCTL()
+	ctl.Path = file
+	if err := ctl.Fetch(context.Background(), nil); err != nil {
+		t.Fatalf("InternalCTL.Fetch() error = %v", err)
+	}
+	if ctl.Vendor() != "Corp PKI" || len(ctl.Trusted) != 2 || len(ctl.Removed) != 1 {
+		t.Fatalf("InternalCTL.Fetch() vendor = %s, trusted = %d, removed = %d, want Corp PKI, 2, 1", ctl.Vendor(), len(ctl.Trusted), len(ctl.Removed))
+	}
+
+	ret := ctl.Verify([]*Cert{current, expired, replaced, other}, Entrys{})
+	if ret.Vendor != "Corp PKI" || len(ret.TrustedCerts) != 1 || len(ret.RemovedCerts) != 2 || len(ret.UnknownCerts) != 1 {
+		t.Errorf("InternalCTL.Verify() = %d trusted, %d removed, %d unknown, want 1, 2, 1", len(ret.TrustedCerts), len(ret.RemovedCerts), len(ret.UnknownCerts))
+	}
+	want := []detail{{Label: "Status", Value: "Expired"}, {Label: "Removed on", Value: "2020-01-01"}, {Label: "Owner", Value: "pki-team@corp.example"}}
+	if got := ret.certDetails(expired); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("InternalCTL.Verify() details of the expired root = %v, want %v", got, want)
+	}
+
+	// a directory of certificates
+	certs := filepath.Join(dir, "certs")
+	if err := os.Mkdir(certs, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for i, cert := range []*Cert{current, expired} {
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		if err := os.WriteFile(filepath.Join(certs, fmt.Sprintf("%d.pem", i)), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctl.Path = certs
+	if err := ctl.Fetch(context.Background(), nil); err != nil {
+		t.Fatalf("InternalCTL.Fetch() of a directory error = %v", err)
+	}
+	if len(ctl.Trusted) != 2 || len(ctl.Removed) != 0 || ctl.Trusted[current.Checksum].Name != "Corp Root CA 2020" {
+		t.Errorf("InternalCTL.Fetch() of a directory trusted = %v, removed = %v", ctl.Trusted, ctl.Removed)
+	}
+
+	ctl.Path = ""
+	if err := ctl.Fetch(context.Background(), nil); err == nil {
+		t.Errorf("InternalCTL.Fetch() without a path, want error")
+	}
+}
@@ -2,12 +2,18 @@ package ctl
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"net/http"
+	"os"
+	"sort"
 	"strings"
 	"time"
 	"unicode/utf16"
@@ -18,12 +24,35 @@ import (
 const (
 	MicrosoftCACertificateReportCSV = "https://ccadb-public.secure.force.com/microsoft/IncludedCACertificateReportForMSFTCSV"
 	MicrosoftAuthrootStl            = "http://ctldl.windowsupdate.com/msdownload/update/v3/static/trustedr/en/authroot.stl"
+	MicrosoftAuthrootCab            = "http://ctldl.windowsupdate.com/msdownload/update/v3/static/trustedr/en/authrootstl.cab"
+	MicrosoftDisallowedStl          = "http://ctldl.windowsupdate.com/msdownload/update/v3/static/trustedr/en/disallowedcert.stl"
+	MicrosoftDeprecationURL         = "https://docs.microsoft.com/en-us/security/trusted-root/deprecation"
 )
 
+// microsoftStatusReasons describes the Microsoft Status values of roots that are not included
+// https://docs.microsoft.com/en-us/security/trusted-root/deprecation
+var microsoftStatusReasons = map[string]string{
+	"Disable": "disabled, no certificate chaining to it is trusted",
+	"Removal": "removed from the Microsoft Trusted Root Program",
+}
+
 type MicrosoftCTL struct {
 	*CTL          `yaml:",inline"`
 	CCADBUrl      string `yaml:"ccadb_url"`
 	CCADBChecksum string `yaml:"ccadb_checksum,omitempty"`
+	AuthrootURL   string `yaml:"authroot_url,omitempty"`
+	DisallowedURL string `yaml:"disallowed_url,omitempty"`
+	// Disallowed maps the SHA-1 thumbprint of certificates in disallowedcert.stl
+	// whose SHA-256 hash is unknown to their entry.
+	Disallowed Entrys `yaml:"disallowed,omitempty"`
+	// ThisUpdate, NextUpdate and SequenceNumber (hex) of the last verified
+	// authroot.stl, to detect stale or rolled back lists
+	ThisUpdate     time.Time `yaml:"this_update,omitempty"`
+	NextUpdate     time.Time `yaml:"next_update,omitempty"`
+	SequenceNumber string    `yaml:"sequence_number,omitempty"`
+	// AuthrootPath is a local authrootstl.cab or authroot.stl read instead
+	// of downloading authrootstl.cab
+	AuthrootPath string `yaml:"authroot_path,omitempty"`
 }
 
 func NewMicrosoftCTL() *MicrosoftCTL {
@@ -31,12 +60,24 @@ func NewMicrosoftCTL() *MicrosoftCTL {
 		CTL:           NewCTL(),
 		CCADBUrl:      MicrosoftCACertificateReportCSV,
 		CCADBChecksum: "",
+		AuthrootURL:   MicrosoftAuthrootCab,
+		DisallowedURL: MicrosoftDisallowedStl,
 	}
 }
 
+// Name of the source, see CTLSource
+func (ctl *MicrosoftCTL) Name() string { return MICROSOFT }
+
+// Vendor name shown in reports, see CTLSource
+func (ctl *MicrosoftCTL) Vendor() string { return "Microsoft" }
+
+// TrustList returns the fetched CTL, see CTLSource
+func (ctl *MicrosoftCTL) TrustList() *CTL { return ctl.CTL }
+
 // Verify that the specified certificate is included in the CTL or has been removed
 func (ctl *MicrosoftCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
 	ret := VerifyResult{
+		Vendor:       ctl.Vendor(),
 		Total:        len(certs),
 		TrustedCerts: []*Cert{},
 		AllowedCerts: []*Cert{},
@@ -46,23 +87,85 @@ func (ctl *MicrosoftCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResul
 		UnknownCerts: []*Cert{},
 		unknownDesc:  "",
 	}
-	ctl.verify(certs, allowedCerts, &ret)
+	ctl.withDisallowed(certs).verify(certs, allowedCerts, &ret)
+	// Windows downloads trusted roots on demand, so roots not used yet on
+	// the system are missing by design and not reported
+	ret.MissingEntries = Entrys{}
 	return &ret
 }
 
-// Fetch Microsoft's CTL from two sources, ccadb and authroot.stl
-func (ctl *MicrosoftCTL) Fetch() error {
+// withDisallowed returns the CTL with the certificates in Disallowed moved
+// to Removed, matched by their SHA-1 thumbprint.
+func (ctl *MicrosoftCTL) withDisallowed(certs []*Cert) *CTL {
+	if len(ctl.Disallowed) == 0 {
+		return ctl.CTL
+	}
+	ret := &CTL{UpdatedAt: ctl.UpdatedAt, Trusted: Entrys{}, Removed: Entrys{}}
+	for k, v := range ctl.Trusted {
+		ret.Trusted[k] = v
+	}
+	for k, v := range ctl.Removed {
+		ret.Removed[k] = v
+	}
+	for _, cert := range certs {
+		thumbprint := sha1.Sum(cert.Raw)
+		if entry, ok := ctl.Disallowed[strings.ToUpper(hex.EncodeToString(thumbprint[:]))]; ok {
+			delete(ret.Trusted, cert.Checksum)
+			ret.Removed[cert.Checksum] = entry
+		}
+	}
+	return ret
+}
+
+// Fetch Microsoft's CTL from three sources, ccadb, authroot.stl and disallowedcert.stl
+func (ctl *MicrosoftCTL) Fetch(ctx context.Context, cl *http.Client) error {
 	if ctl.CTL == nil {
 		ctl.CTL = NewCTL()
 	}
 
-	body, err := getBody(MicrosoftCACertificateReportCSV)
+	var body, authroot, disallowed []byte
+	err := fetchConcurrently(
+		func() (err error) { body, err = getBody(ctx, cl, ctl.CCADBUrl); return err },
+		func() (err error) { authroot, err = ctl.getAuthroot(ctx, cl); return err },
+		func() (err error) { disallowed, err = getBody(ctx, cl, ctl.DisallowedURL); return err },
+	)
+	if err != nil {
+		return err
+	}
+
+	// verify both lists before anything is changed, they are fetched over plain HTTP
+	now := time.Now()
+	list, err := ctl.checkAuthroot(authroot, now)
+	if err != nil {
+		return err
+	}
+	if err := checkDisallowed(disallowed, now); err != nil {
+		return err
+	}
+	items, err := parseAuthroot(authroot)
+	if err != nil {
+		return err
+	}
+	disallowedItems, thumbprints, err := parseDisallowed(disallowed)
 	if err != nil {
 		return err
 	}
 
+	// the CTL is only updated if all sources are valid, so build it aside.
+	// Entries of an older version are replaced by parsing the report again.
+	legacy := ctl.legacy()
+	trusted, removed := Entrys{}, Entrys{}
+	if !legacy {
+		for k, v := range ctl.Trusted {
+			trusted[k] = v
+		}
+		for k, v := range ctl.Removed {
+			removed[k] = v
+		}
+	}
+
 	hash := getChecksum(body)
-	if hash != ctl.CCADBChecksum { // updated
+	if legacy || hash != ctl.CCADBChecksum { // updated
 		c, err := csvReadToMap(bytes.NewReader(body))
 		if err != nil {
 			return fmt.Errorf("read csv file err: %w", err)
@@ -76,45 +179,71 @@ func (ctl *MicrosoftCTL) Fetch() error {
 			case "", "Example Root Case", "Example Root Certificate":
 				continue
 			default:
+				entry := Entry{
+					Name:   name,
+					Status: v["Microsoft Status"],
+					Usages: splitList(v["Microsoft EKUs"]),
+					Source: SourceCCADB,
+				}
 				// https://docs.microsoft.com/en-us/security/trusted-root/deprecation
-				switch v["Microsoft Status"] {
+				switch entry.Status {
 				case "Included", "NotBefore":
-					ctl.Trusted[sha256] = name
+					if date, err := parseDate(strings.TrimSpace(v["NotBefore Date"])); err == nil && entry.Status == "NotBefore" {
+						entry.Constraint = &Constraint{DistrustTLSAfter: date, DistrustEmailAfter: date}
+						entry.URL = MicrosoftDeprecationURL
+					}
+					trusted[sha256] = entry
 				// case "NotBefore", "Removal":
-				// 	ctl.Removed[sha256] = name
+				// 	removed[sha256] = name
 				default:
-					ctl.Removed[sha256] = name
+					entry.Reason = microsoftStatusReasons[entry.Status]
+					entry.URL = MicrosoftDeprecationURL
+					removed[sha256] = entry
 				}
 			}
 		}
-		ctl.CCADBChecksum = hash
 	}
 
-	authroot, err := getBody(MicrosoftAuthrootStl)
-	if err != nil {
-		return err
-	}
-	items, err := parseAuthroot(authroot)
-	if err != nil {
-		return err
-	}
 	for k, v := range items {
-		_, ok := ctl.Removed[k]
-		if ok {
+		if _, ok := removed[k]; ok {
 			continue
 		}
-		ctl.Trusted[k] = v
+		if v.Status == "Disable" {
+			delete(trusted, k)
+			removed[k] = v
+			continue
+		}
+		if entry, ok := trusted[k]; ok { // the ccadb entry has more details
+			if entry.Constraint == nil {
+				entry.Constraint = v.Constraint
+			}
+			if len(entry.Usages) == 0 {
+				entry.Usages = v.Usages
+			}
+			trusted[k] = entry
+			continue
+		}
+		trusted[k] = v
+	}
+
+	for k, v := range disallowedItems {
+		delete(trusted, k)
+		removed[k] = v
 	}
 
 	// OS built-in, not included in authroot.stl or ccadb.
 	// https://docs.microsoft.com/en-us/troubleshoot/windows-server/identity/trusted-root-certificates-are-required
 	// -- Friendly name: Microsoft Authenticode(tm) Root
 	// -- Thumbprint: 7f88cd7223f3c813818c994614a89c99fa3b5247
-	ctl.Trusted["4898B1749717A594A2030F47C83C272BD14BAE3DCEB2EAE382174EF2EC1C75C9"] = "Microsoft Authenticode(tm) Root Authority"
+	trusted["4898B1749717A594A2030F47C83C272BD14BAE3DCEB2EAE382174EF2EC1C75C9"] = Entry{Name: "Microsoft Authenticode(tm) Root Authority", Source: SourceBuiltIn}
 	// -- Friendly name: Microsoft Timestamp Root
 	// -- Thumbprint: 245c97df7514e7cf2df8be72ae957b9e04741e85
-	ctl.Trusted["6EF914723F089D2ADAFF98D470A3651CCF1768E559FBDCC0FAAA640AA12E5753"] = "Microsoft Timestamp Root"
+	trusted["6EF914723F089D2ADAFF98D470A3651CCF1768E559FBDCC0FAAA640AA12E5753"] = Entry{Name: "Microsoft Timestamp Root", Source: SourceBuiltIn}
 
+	ctl.Trusted, ctl.Removed = trusted, removed
+	ctl.Disallowed = thumbprints
+	ctl.CCADBChecksum = hash
+	ctl.setAuthroot(list)
 	ctl.UpdatedAt = time.Now()
 	return nil
 }
@@ -122,10 +251,21 @@ func (ctl *MicrosoftCTL) Fetch() error {
 var (
 	// RFC3852 CMS message, ContentType Object Identifier for Certificate Trust List (CTL)
 	szOID_CTL = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 1}
+	// Microsoft Trust List Signing, CAs of the CTL signers must have it
+	szOID_KP_CTL_USAGE_SIGNING = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 3, 1}
 	// Signer of a CTL containing trusted roots
-	szOID_ROOT_LIST_SIGNER           = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 3, 9}
+	szOID_ROOT_LIST_SIGNER = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 3, 9}
+	// Signer of a CTL containing disallowed certificates
+	szOID_DISALLOWED_LIST            = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 3, 30}
 	szOID_CERT_FRIENDLY_NAME_PROP_ID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 11}
 	szOID_CERT_AUTHROOT_SHA256_HASH  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 98}
+	// Properties of a root, named after CERT_*_PROP_ID in wincrypt.h
+	szOID_CERT_ENHKEY_USAGE_PROP_ID            = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 9}
+	szOID_CERT_ROOT_PROGRAM_CERT_POLICIES      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 83}
+	szOID_CERT_DISALLOWED_FILETIME_PROP_ID     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 104}
+	szOID_CERT_DISALLOWED_ENHKEY_USAGE_PROP_ID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 122}
+	szOID_CERT_NOT_BEFORE_FILETIME_PROP_ID     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 126}
+	szOID_CERT_NOT_BEFORE_ENHKEY_USAGE_PROP_ID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 11, 127}
 	// szOID_AUTO_ENROLL_CTL_USAGE      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 1}
 )
 
@@ -152,52 +292,446 @@ type attribute struct {
 	Value asn1.RawValue `asn1:"set"`
 }
 
-func parseAuthroot(b []byte) (Entrys, error) {
-	ret := Entrys{}
+// ekuNames maps the extended key usages of Microsoft roots to the names used by CCADB
+var ekuNames = map[string]string{
+	"1.3.6.1.5.5.7.3.1":       "Server Authentication",
+	"1.3.6.1.5.5.7.3.2":       "Client Authentication",
+	"1.3.6.1.5.5.7.3.3":       "Code Signing",
+	"1.3.6.1.5.5.7.3.4":       "Secure Email",
+	"1.3.6.1.5.5.7.3.5":       "IP security end system",
+	"1.3.6.1.5.5.7.3.6":       "IP security tunnel termination",
+	"1.3.6.1.5.5.7.3.7":       "IP security user",
+	"1.3.6.1.5.5.7.3.8":       "Time Stamping",
+	"1.3.6.1.5.5.7.3.9":       "OCSP Signing",
+	"1.3.6.1.4.1.311.10.3.4":  "Encrypting File System",
+	"1.3.6.1.4.1.311.10.3.12": "Document Signing",
+	"1.3.6.1.5.5.8.2.2":       "IP security IKE intermediate",
+}
+
+// stlSubject is a certificate in a Microsoft CTL (.stl)
+type stlSubject struct {
+	// Thumbprint is the SHA-1 hash of the certificate
+	Thumbprint   string
+	SHA256       string
+	FriendlyName string
+	// EKUs the root is trusted for, empty for any
+	EKUs         []string
+	EVPolicyOIDs []string
+	// DisallowedAt is when the root was disabled, for DisallowedEKUs only if set
+	DisallowedAt   time.Time
+	DisallowedEKUs []string
+	// Certificates issued after NotBefore are not trusted, for NotBeforeEKUs only if set
+	NotBefore     time.Time
+	NotBeforeEKUs []string
+}
 
+// parseSTL parses a Microsoft CTL signed for usage
+func parseSTL(b []byte, usage asn1.ObjectIdentifier) ([]stlSubject, error) {
 	content, err := getUnsignedData(b, szOID_CTL)
 	if err != nil {
-		return ret, err
+		return nil, err
 	}
 
 	var ctl certificateTrustList
 	_, err = asn1.Unmarshal(content, &ctl)
 	if err != nil {
-		return ret, fmt.Errorf("parse error, %v", err)
+		return nil, fmt.Errorf("parse error, %v", err)
 	}
 
-	if len(ctl.SubjectUsage) != 1 || !ctl.SubjectUsage[0].Equal(szOID_ROOT_LIST_SIGNER) {
-		return ret, fmt.Errorf("unknown SubjectUsage")
+	if len(ctl.SubjectUsage) != 1 || !ctl.SubjectUsage[0].Equal(usage) {
+		return nil, fmt.Errorf("unknown SubjectUsage")
 	}
 
+	ret := []stlSubject{}
 	for _, subject := range ctl.Subjects {
-		// thumbprint := strings.ToUpper(hex.EncodeToString(subject.Thumbprint))
-		var sha256Hash, friendlyName string
+		item := stlSubject{
+			Thumbprint: strings.ToUpper(hex.EncodeToString(subject.Thumbprint)),
+		}
 		for _, attr := range subject.Attributes {
 			var value []byte
 			_, err := asn1.Unmarshal(attr.Value.Bytes, &value)
 			if err != nil {
-				return ret, err
+				return nil, err
 			}
 			switch attr.Type.String() {
 			case szOID_CERT_AUTHROOT_SHA256_HASH.String():
-				sha256Hash = strings.ToUpper(hex.EncodeToString(value))
+				item.SHA256 = strings.ToUpper(hex.EncodeToString(value))
 			case szOID_CERT_FRIENDLY_NAME_PROP_ID.String():
 				name, err := wstrToString(value)
 				if err != nil {
-					return ret, err
+					return nil, err
 				}
-				friendlyName = name
+				item.FriendlyName = name
+			case szOID_CERT_ENHKEY_USAGE_PROP_ID.String():
+				item.EKUs, err = parseEKUs(value)
+			case szOID_CERT_ROOT_PROGRAM_CERT_POLICIES.String():
+				item.EVPolicyOIDs, err = parseRootProgramPolicies(value)
+			case szOID_CERT_DISALLOWED_FILETIME_PROP_ID.String():
+				item.DisallowedAt, err = filetimeToTime(value)
+			case szOID_CERT_DISALLOWED_ENHKEY_USAGE_PROP_ID.String():
+				item.DisallowedEKUs, err = parseEKUs(value)
+			case szOID_CERT_NOT_BEFORE_FILETIME_PROP_ID.String():
+				item.NotBefore, err = filetimeToTime(value)
+			case szOID_CERT_NOT_BEFORE_ENHKEY_USAGE_PROP_ID.String():
+				item.NotBeforeEKUs, err = parseEKUs(value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("subject %s, attribute %v: %w", item.Thumbprint, attr.Type, err)
 			}
 		}
-		if sha256Hash != "" {
-			ret[sha256Hash] = friendlyName
+		ret = append(ret, item)
+	}
+
+	return ret, nil
+}
+
+// getAuthroot reads AuthrootPath if set, otherwise fetches AuthrootURL
+func (ctl *MicrosoftCTL) getAuthroot(ctx context.Context, cl *http.Client) ([]byte, error) {
+	if ctl.AuthrootPath != "" {
+		return os.ReadFile(ctl.AuthrootPath)
+	}
+	return getBody(ctx, cl, ctl.AuthrootURL)
+}
+
+// parseAuthroot parses authroot.stl, or the authrootstl.cab containing it
+func parseAuthroot(b []byte) (Entrys, error) {
+	ret := Entrys{}
+	b, err := fromCAB(b, "authroot.stl")
+	if err != nil {
+		return ret, err
+	}
+	subjects, err := parseSTL(b, szOID_ROOT_LIST_SIGNER)
+	if err != nil {
+		return ret, err
+	}
+	for _, subject := range subjects {
+		if subject.SHA256 != "" {
+			ret[subject.SHA256] = subject.entry()
 		}
 	}
+	return ret, nil
+}
+
+// entry returns the entry of a root in authroot.stl. Roots disabled for all
+// usages have the status "Disable", roots distrusted for certificates issued
+// after a date have the status "NotBefore".
+func (s stlSubject) entry() Entry {
+	entry := Entry{
+		Name:   s.FriendlyName,
+		Source: SourceAuthroot,
+	}
+	for _, eku := range s.EKUs {
+		if !containsString(s.DisallowedEKUs, eku) {
+			entry.Usages = append(entry.Usages, ekuName(eku))
+		}
+	}
+	if len(s.EVPolicyOIDs) > 0 {
+		entry.Constraint = &Constraint{EVPolicyOIDs: s.EVPolicyOIDs}
+	}
+	if !s.DisallowedAt.IsZero() {
+		if len(s.DisallowedEKUs) == 0 {
+			entry.Status = "Disable"
+			entry.RemovedAt = s.DisallowedAt
+			entry.Reason = microsoftStatusReasons[entry.Status]
+			entry.URL = MicrosoftDeprecationURL
+			return entry
+		}
+		if len(s.EKUs) == 0 { // trusted for any usage but the disallowed ones
+			for oid, name := range ekuNames {
+				if !containsString(s.DisallowedEKUs, oid) {
+					entry.Usages = append(entry.Usages, name)
+				}
+			}
+			sort.Strings(entry.Usages)
+		}
+	}
+	if !s.NotBefore.IsZero() {
+		entry.Status = "NotBefore"
+		entry.URL = MicrosoftDeprecationURL
+		c := Constraint{}
+		if entry.Constraint != nil {
+			c = *entry.Constraint
+		}
+		if len(s.NotBeforeEKUs) == 0 || containsString(s.NotBeforeEKUs, "1.3.6.1.5.5.7.3.1") {
+			c.DistrustTLSAfter = s.NotBefore
+		}
+		if len(s.NotBeforeEKUs) == 0 || containsString(s.NotBeforeEKUs, "1.3.6.1.5.5.7.3.4") {
+			c.DistrustEmailAfter = s.NotBefore
+		}
+		if c.distrustsAfter() || len(c.EVPolicyOIDs) > 0 {
+			entry.Constraint = &c
+		}
+	}
+	return entry
+}
+
+func ekuName(oid string) string {
+	if name, ok := ekuNames[oid]; ok {
+		return name
+	}
+	return oid
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEKUs parses a CERT_ENHKEY_USAGE property, a SEQUENCE OF OBJECT IDENTIFIER
+func parseEKUs(b []byte) ([]string, error) {
+	var oids []asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(b, &oids); err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(oids))
+	for _, oid := range oids {
+		ret = append(ret, oid.String())
+	}
+	return ret, nil
+}
 
+// parseRootProgramPolicies parses the CERT_ROOT_PROGRAM_CERT_POLICIES property,
+// a sequence of policy information with the EV policy OIDs of the root
+func parseRootProgramPolicies(b []byte) ([]string, error) {
+	var policies []struct {
+		Policy     asn1.ObjectIdentifier
+		Qualifiers asn1.RawValue `asn1:"optional"`
+	}
+	if _, err := asn1.Unmarshal(b, &policies); err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(policies))
+	for _, p := range policies {
+		ret = append(ret, p.Policy.String())
+	}
 	return ret, nil
 }
 
+// filetimeToTime converts a little endian Windows FILETIME, the number of 100
+// nanoseconds since January 1, 1601 UTC. An empty value is the zero time.
+func filetimeToTime(b []byte) (time.Time, error) {
+	if len(b) == 0 {
+		return time.Time{}, nil
+	}
+	if len(b) != 8 {
+		return time.Time{}, fmt.Errorf("FILETIME of %d bytes", len(b))
+	}
+	// seconds between 1601-01-01 and 1970-01-01
+	const epochDelta = 11644473600
+	ft := binary.LittleEndian.Uint64(b)
+	return time.Unix(int64(ft/1e7)-epochDelta, int64(ft%1e7)*100).UTC(), nil
+}
+
+// parseDisallowed parses disallowedcert.stl. Its subjects are identified by
+// their SHA-1 thumbprint, which is returned in thumbprints unless the SHA-256
+// hash of the certificate is also known.
+func parseDisallowed(b []byte) (removed Entrys, thumbprints Entrys, err error) {
+	removed, thumbprints = Entrys{}, Entrys{}
+	if b, err = fromCAB(b, "disallowedcert.stl"); err != nil {
+		return removed, thumbprints, err
+	}
+	subjects, err := parseSTL(b, szOID_DISALLOWED_LIST)
+	if err != nil {
+		return removed, thumbprints, err
+	}
+	for _, subject := range subjects {
+		entry := Entry{
+			Name:   subject.FriendlyName,
+			Status: "Disallowed",
+			Reason: "explicitly distrusted in disallowedcert.stl",
+			URL:    MicrosoftDisallowedStl,
+			Source: SourceDisallowed,
+		}
+		switch {
+		case subject.SHA256 != "":
+			removed[subject.SHA256] = entry
+		case len(subject.Thumbprint) == 2*sha1.Size:
+			thumbprints[subject.Thumbprint] = entry
+		}
+	}
+	return removed, thumbprints, nil
+}
+
+// maxAuthrootAge is the age after which an authroot.stl without a next update
+// is stale. Windows Update publishes a new one every few weeks.
+const maxAuthrootAge = 180 * 24 * time.Hour
+
+// checkAuthroot verifies the signature of authroot.stl, which is fetched over
+// plain HTTP, and that it is neither stale nor older than the last one. The
+// CTL is not changed, see setAuthroot.
+func (ctl *MicrosoftCTL) checkAuthroot(b []byte, now time.Time) (*certificateTrustList, error) {
+	b, err := fromCAB(b, "authroot.stl")
+	if err != nil {
+		return nil, fmt.Errorf("verify authroot.stl: %w", err)
+	}
+	list, err := verifySTL(b, szOID_ROOT_LIST_SIGNER, now)
+	if err != nil {
+		return nil, fmt.Errorf("verify authroot.stl: %w", err)
+	}
+	if !list.CTLNextUpdate.IsZero() && list.CTLNextUpdate.Before(now) {
+		return nil, fmt.Errorf("authroot.stl is stale, next update was %s", list.CTLNextUpdate.Format(time.RFC3339))
+	}
+	if list.CTLNextUpdate.IsZero() && list.CTLThisUpdate.Add(maxAuthrootAge).Before(now) {
+		return nil, fmt.Errorf("authroot.stl is stale, last updated %s", list.CTLThisUpdate.Format(time.RFC3339))
+	}
+	if last, ok := new(big.Int).SetString(ctl.SequenceNumber, 16); ok && list.sequenceNumber().Cmp(last) < 0 {
+		return nil, fmt.Errorf("authroot.stl rolled back from sequence number %s to %s", ctl.SequenceNumber, list.sequenceNumber().Text(16))
+	}
+	if list.CTLThisUpdate.Before(ctl.ThisUpdate) {
+		return nil, fmt.Errorf("authroot.stl rolled back from %s to %s", ctl.ThisUpdate.Format(time.RFC3339), list.CTLThisUpdate.Format(time.RFC3339))
+	}
+	return list, nil
+}
+
+// setAuthroot records the update and sequence number of the verified list
+func (ctl *MicrosoftCTL) setAuthroot(list *certificateTrustList) {
+	ctl.ThisUpdate = list.CTLThisUpdate
+	ctl.NextUpdate = list.CTLNextUpdate
+	ctl.SequenceNumber = list.sequenceNumber().Text(16)
+}
+
+func (list *certificateTrustList) sequenceNumber() *big.Int {
+	if list.SequenceNumber != nil {
+		return list.SequenceNumber
+	}
+	return new(big.Int)
+}
+
+// checkDisallowed verifies the signature of disallowedcert.stl, which is
+// fetched over plain HTTP like authroot.stl.
+func checkDisallowed(b []byte, now time.Time) error {
+	b, err := fromCAB(b, "disallowedcert.stl")
+	if err != nil {
+		return fmt.Errorf("verify disallowedcert.stl: %w", err)
+	}
+	if _, err := verifySTL(b, szOID_DISALLOWED_LIST, now); err != nil {
+		return fmt.Errorf("verify disallowedcert.stl: %w", err)
+	}
+	return nil
+}
+
+// verifySTL verifies the signature of a Microsoft CTL, and that its signer
+// has the usage and chains to one of the pinned microsoftCTLRoots at the time
+// of the CTL's ThisUpdate.
+func verifySTL(b []byte, usage asn1.ObjectIdentifier, now time.Time) (*certificateTrustList, error) {
+	return verifySTLWithRoots(b, usage, now, microsoftCTLRoots)
+}
+
+// verifySTLWithRoots is verifySTL with the trust anchors roots. The CAs
+// between the signer and the root must be allowed to sign CTLs.
+func verifySTLWithRoots(b []byte, usage asn1.ObjectIdentifier, now time.Time, roots *x509.CertPool) (*certificateTrustList, error) {
+	ci, err := protocol.ParseContentInfo(b)
+	if err != nil {
+		return nil, err
+	}
+	sd, err := ci.SignedDataContent()
+	if err != nil {
+		return nil, err
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(szOID_CTL) {
+		return nil, fmt.Errorf("wrong contentType: %v", sd.EncapContentInfo.EContentType)
+	}
+	content := sd.EncapContentInfo.EContent.Bytes
+	var list certificateTrustList
+	if _, err := asn1.Unmarshal(content, &list); err != nil {
+		return nil, fmt.Errorf("parse error, %v", err)
+	}
+	if list.CTLThisUpdate.After(now.Add(24 * time.Hour)) {
+		return nil, fmt.Errorf("this update %s is in the future", list.CTLThisUpdate.Format(time.RFC3339))
+	}
+
+	certs, err := sd.X509Certificates()
+	if err != nil {
+		return nil, err
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("%d signers, want 1", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+	signer, err := si.FindCertificate(certs)
+	if err != nil {
+		return nil, err
+	}
+
+	// the content is not an OCTET STRING, as in PKCS #7 only the value
+	// octets of its DER encoding are digested
+	var value asn1.RawValue
+	if _, err := asn1.Unmarshal(content, &value); err != nil {
+		return nil, err
+	}
+	hash, err := si.Hash()
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write(value.Bytes)
+	digest, err := si.GetMessageDigestAttribute()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(digest, h.Sum(nil)) {
+		return nil, fmt.Errorf("message digest mismatch")
+	}
+	signed, err := si.SignedAttrs.MarshaledForVerification()
+	if err != nil {
+		return nil, err
+	}
+	if err := signer.CheckSignature(si.X509SignatureAlgorithm(), signed, si.Signature); err != nil {
+		return nil, err
+	}
+
+	hasUsage := false
+	for _, eku := range signer.UnknownExtKeyUsage {
+		hasUsage = hasUsage || eku.Equal(usage)
+	}
+	if !hasUsage {
+		return nil, fmt.Errorf("signer %q is not allowed to sign %v", signer.Subject.CommonName, usage)
+	}
+
+	// the Microsoft EKUs are unknown to crypto/x509, they are checked below
+	opts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		Roots:         roots,
+		CurrentTime:   list.CTLThisUpdate,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, cert := range certs {
+		opts.Intermediates.AddCert(cert)
+	}
+	chains, err := signer.Verify(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range chains {
+		if ctlSigningChain(chain) {
+			return &list, nil
+		}
+	}
+	return nil, fmt.Errorf("the CAs of signer %q are not allowed to sign CTLs", signer.Subject.CommonName)
+}
+
+// ctlSigningChain reports whether the CAs between the signer and the root of
+// chain are allowed to sign CTLs (Microsoft Trust List Signing).
+func ctlSigningChain(chain []*x509.Certificate) bool {
+	for _, ca := range chain[1 : len(chain)-1] {
+		allowed := false
+		for _, eku := range ca.UnknownExtKeyUsage {
+			allowed = allowed || eku.Equal(szOID_KP_CTL_USAGE_SIGNING)
+		}
+		for _, eku := range ca.ExtKeyUsage {
+			allowed = allowed || eku == x509.ExtKeyUsageAny
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
 // getUnsignedData parse CMS (rfc3852) message, return eContent
 func getUnsignedData(b []byte, contentType asn1.ObjectIdentifier) ([]byte, error) {
 	ci, err := protocol.ParseContentInfo(b)
@@ -1,8 +1,25 @@
 package ctl
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/github/smimesign/ietf-cms/protocol"
 )
 
 func Test_parseAuthroot(t *testing.T) {
@@ -13,6 +30,10 @@ func Test_parseAuthroot(t *testing.T) {
 	if err != nil {
 		t.Fatalf("parseAuthroot() error: %v", err)
 	}
+	cabAuthroot, err := os.ReadFile("testdata/authrootstl.cab")
+	if err != nil {
+		t.Fatalf("parseAuthroot() error: %v", err)
+	}
 	tests := []struct {
 		name       string
 		args       args
@@ -35,6 +56,14 @@ func Test_parseAuthroot(t *testing.T) {
 			wantLength: 436,
 			wantErr:    false,
 		},
+		{
+			name: "real authrootstl.cab",
+			args: args{
+				b: cabAuthroot,
+			},
+			wantLength: 436,
+			wantErr:    false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -53,9 +82,255 @@ func Test_parseAuthroot(t *testing.T) {
 	}
 }
 
+func Test_parseAuthroot_attributes(t *testing.T) {
+	stlAuthroot, err := os.ReadFile("testdata/authroot.stl")
+	if err != nil {
+		t.Fatalf("parseAuthroot() error: %v", err)
+	}
+	got, err := parseAuthroot(stlAuthroot)
+	if err != nil {
+		t.Fatalf("parseAuthroot() error = %v", err)
+	}
+
+	disabled := got["A31F093053BD12C1F5C3C6EFD498023FD2914D7758D05D698CE084B50626E0E5"]
+	if disabled.Name != "BIT Admin-Root-CA" || disabled.Status != "Disable" || disabled.RemovedAt.Format("2006-01-02") != "2016-09-20" {
+		t.Errorf("parseAuthroot() disabled entry = %+v, want disabled since 2016-09-20", disabled)
+	}
+
+	notBefore := got["3C4FB0B95AB8B30032F432B86F535FE172C185D0FD39865837CF36187FA6F428"]
+	if notBefore.Status != "NotBefore" || notBefore.Constraint == nil || notBefore.Constraint.DistrustTLSAfter.Format("2006-01-02") != "2021-02-01" {
+		t.Errorf("parseAuthroot() NotBefore entry = %+v, want distrust for TLS after 2021-02-01", notBefore)
+	}
+	if !notBefore.Trusts(TrustWebsites) || !notBefore.PartiallyDistrusted() {
+		t.Errorf("parseAuthroot() NotBefore entry usages = %v, want partially distrusted for websites", notBefore.Usages)
+	}
+
+	// trusted for time stamping only
+	timestamping := got["5B789987F3C4055B8700941B33783A5F16E0CFF937EA32011FE04779F7635308"]
+	if len(timestamping.Usages) != 1 || timestamping.Usages[0] != "Time Stamping" {
+		t.Errorf("parseAuthroot() usages = %v, want [Time Stamping]", timestamping.Usages)
+	}
+}
+
+func TestMicrosoftCTL_checkAuthroot(t *testing.T) {
+	stlAuthroot, err := os.ReadFile("testdata/authroot.stl")
+	if err != nil {
+		t.Fatalf("checkAuthroot() error: %v", err)
+	}
+	now := time.Date(2021, 10, 20, 0, 0, 0, 0, time.UTC)
+
+	ctl := NewMicrosoftCTL()
+	list, err := ctl.checkAuthroot(stlAuthroot, now)
+	if err != nil {
+		t.Fatalf("checkAuthroot() error = %v", err)
+	}
+	if ctl.SequenceNumber != "" {
+		t.Errorf("checkAuthroot() changed the CTL, sequence number = %s", ctl.SequenceNumber)
+	}
+	ctl.setAuthroot(list)
+	if ctl.SequenceNumber != "1401d7c45cc6489fe7" || ctl.ThisUpdate.Format(time.RFC3339) != "2021-10-18T20:14:37Z" {
+		t.Errorf("setAuthroot() sequence number = %s, this update = %v", ctl.SequenceNumber, ctl.ThisUpdate)
+	}
+
+	// the same list again is fine, an older one is not
+	if _, err := ctl.checkAuthroot(stlAuthroot, now); err != nil {
+		t.Errorf("checkAuthroot() of the same list error = %v", err)
+	}
+	ctl.SequenceNumber = "1401d7c45cc6489fe8"
+	if _, err := ctl.checkAuthroot(stlAuthroot, now); err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Errorf("checkAuthroot() of an older list error = %v, want rolled back", err)
+	}
+
+	// the list has no next update, it is stale long after its this update
+	later := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := NewMicrosoftCTL().checkAuthroot(stlAuthroot, later); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("checkAuthroot() of a list from 2021 in 2026 error = %v, want stale", err)
+	}
+
+	tampered := bytes.Clone(stlAuthroot)
+	i := bytes.Index(tampered, []byte("M\x00i\x00c\x00r\x00o\x00s\x00o\x00f\x00t\x00"))
+	tampered[i] = 'm'
+	if _, err := verifySTL(tampered, szOID_ROOT_LIST_SIGNER, now); err == nil {
+		t.Errorf("verifySTL() of a tampered list, want error")
+	}
+	if _, err := verifySTL(makeSTL(t, szOID_ROOT_LIST_SIGNER, nil), szOID_ROOT_LIST_SIGNER, now); err == nil {
+		t.Errorf("verifySTL() of an unsigned list, want error")
+	}
+	if _, err := verifySTL(stlAuthroot, szOID_DISALLOWED_LIST, now); err == nil {
+		t.Errorf("verifySTL() for another usage, want error")
+	}
+
+	// disallowedcert.stl is verified like authroot.stl
+	for name, b := range map[string][]byte{
+		"unsigned":      makeSTL(t, szOID_DISALLOWED_LIST, nil),
+		"tampered":      tampered,
+		"another usage": stlAuthroot,
+		"not a CTL":     []byte("disallowed"),
+	} {
+		if err := checkDisallowed(b, now); err == nil {
+			t.Errorf("checkDisallowed() of a %s list, want error", name)
+		}
+	}
+}
+
+func Test_verifySTL(t *testing.T) {
+	// the captured authroot.stl chains to the pinned Microsoft Root Certificate Authority 2010
+	stlAuthroot, err := os.ReadFile("testdata/authroot.stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 10, 20, 0, 0, 0, 0, time.UTC)
+	if list, err := verifySTL(stlAuthroot, szOID_ROOT_LIST_SIGNER, now); err != nil || len(list.Subjects) == 0 {
+		t.Errorf("verifySTL() of the captured authroot.stl error = %v", err)
+	}
+	if _, err := verifySTLWithRoots(stlAuthroot, szOID_ROOT_LIST_SIGNER, now, x509.NewCertPool()); err == nil {
+		t.Errorf("verifySTL() without the pinned roots, want error")
+	}
+	pinned := 0
+	for rest := []byte(microsoftCTLRootsPEM); ; pinned++ {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if _, ok := microsoftCTLRootCAs[getChecksum(block.Bytes)]; !ok {
+			t.Errorf("microsoftCTLRootsPEM has an unpinned certificate %s", getChecksum(block.Bytes))
+		}
+	}
+	if pinned != len(microsoftCTLRootCAs) {
+		t.Errorf("microsoftCTLRootsPEM has %d certificates, want %d", pinned, len(microsoftCTLRootCAs))
+	}
+
+	// a disallowedcert.stl signed like the real one, by a CA allowed to sign CTLs
+	now = time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC) // ThisUpdate of makeSTL
+	root, rootKey := newTestCA(t, "Test Root", nil, nil, nil)
+	ca, caKey := newTestCA(t, "Test Certificate List CA", root, rootKey, []asn1.ObjectIdentifier{szOID_KP_CTL_USAGE_SIGNING})
+	signer, signerKey := newTestCA(t, "Test Trust List Publisher", ca, caKey, []asn1.ObjectIdentifier{szOID_DISALLOWED_LIST})
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	stl := signSTL(t, makeSTL(t, szOID_DISALLOWED_LIST, nil), signerKey, signer, ca)
+	if _, err := verifySTLWithRoots(stl, szOID_DISALLOWED_LIST, now, roots); err != nil {
+		t.Errorf("verifySTL() of a signed disallowedcert.stl error = %v", err)
+	}
+	if _, err := verifySTL(stl, szOID_DISALLOWED_LIST, now); err == nil {
+		t.Errorf("verifySTL() of a list signed under another root, want error")
+	}
+
+	other, otherKey := newTestCA(t, "Test Code Signing CA", root, rootKey, nil)
+	otherSigner, otherSignerKey := newTestCA(t, "Test Trust List Publisher", other, otherKey, []asn1.ObjectIdentifier{szOID_DISALLOWED_LIST})
+	stl = signSTL(t, makeSTL(t, szOID_DISALLOWED_LIST, nil), otherSignerKey, otherSigner, other)
+	if _, err := verifySTLWithRoots(stl, szOID_DISALLOWED_LIST, now, roots); err == nil || !strings.Contains(err.Error(), "not allowed to sign CTLs") {
+		t.Errorf("verifySTL() of a list signed under a CA without Microsoft Trust List Signing error = %v", err)
+	}
+}
+
+// newTestCA returns a CA certificate with the ekus, issued by parent or self-signed
+func newTestCA(t *testing.T, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, ekus []asn1.ObjectIdentifier) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		UnknownExtKeyUsage:    ekus,
+	}
+	if parent == nil {
+		parent, parentKey = tpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// signSTL signs the CTL made by makeSTL with key, like Microsoft only the
+// value octets of the CTL are digested
+func signSTL(t *testing.T, stl []byte, key *ecdsa.PrivateKey, chain ...*x509.Certificate) []byte {
+	t.Helper()
+	ci, err := protocol.ParseContentInfo(stl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := ci.SignedDataContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value asn1.RawValue
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &value); err != nil {
+		t.Fatal(err)
+	}
+	// AddSignerInfo only digests OCTET STRING contents, sign the value octets as one
+	octets, err := protocol.NewEncapsulatedContentInfo(szOID_CTL, value.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := protocol.NewSignedData(octets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signed.AddSignerInfo(chain, key); err != nil {
+		t.Fatal(err)
+	}
+	signed.EncapContentInfo = sd.EncapContentInfo
+	der, err := signed.ContentInfoDER()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestMicrosoftCTL_Fetch_unverified(t *testing.T) {
+	dir := t.TempDir()
+	csv := filepath.Join(dir, "ccadb.csv")
+	if err := os.WriteFile(csv, []byte("CA Common Name or Certificate Name,SHA-256 Fingerprint,Microsoft Status\nRoot,AA,Included\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	disallowed := filepath.Join(dir, "disallowedcert.stl")
+	if err := os.WriteFile(disallowed, makeSTL(t, szOID_DISALLOWED_LIST, nil), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the authroot.stl of 2021 is stale, the disallowedcert.stl unsigned
+	ctl := NewMicrosoftCTL()
+	ctl.CCADBUrl = "file://" + filepath.ToSlash(csv)
+	ctl.DisallowedURL = "file://" + filepath.ToSlash(disallowed)
+	ctl.AuthrootPath = "testdata/authroot.stl"
+	if err := ctl.Fetch(context.Background(), nil); err == nil {
+		t.Fatalf("MicrosoftCTL.Fetch() of unverified lists, want error")
+	}
+	if len(ctl.Trusted) != 0 || len(ctl.Removed) != 0 || ctl.CCADBChecksum != "" || ctl.SequenceNumber != "" {
+		t.Errorf("MicrosoftCTL.Fetch() failed, but changed the CTL: %d trusted, %d removed, checksum %q, sequence number %q",
+			len(ctl.Trusted), len(ctl.Removed), ctl.CCADBChecksum, ctl.SequenceNumber)
+	}
+}
+
+func Test_filetimeToTime(t *testing.T) {
+	got, err := filetimeToTime([]byte{0x00, 0x80, 0xc8, 0x2b, 0x68, 0x86, 0xd7, 0x01})
+	if err != nil {
+		t.Fatalf("filetimeToTime() error = %v", err)
+	}
+	if want := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("filetimeToTime() = %v, want %v", got, want)
+	}
+	if got, err := filetimeToTime(nil); err != nil || !got.IsZero() {
+		t.Errorf("filetimeToTime(nil) = %v, %v, want zero time", got, err)
+	}
+}
+
 func TestMicrosoftCTL_Fetch(t *testing.T) {
 	ctl := NewMicrosoftCTL()
-	err := ctl.Fetch()
+	err := ctl.Fetch(context.Background(), nil)
 	if err != nil {
 		t.Errorf("MicrosoftCTL.Fetch() error = %v", err)
 	}
@@ -63,3 +338,83 @@ func TestMicrosoftCTL_Fetch(t *testing.T) {
 		t.Errorf("MicrosoftCTL.Fetch() error = %v", "no trusted certs, may be parse error")
 	}
 }
+
+// makeSTL returns a Microsoft CTL for usage, with the certificates identified
+// by their SHA-1 thumbprint and named by a friendly name.
+func makeSTL(t *testing.T, usage asn1.ObjectIdentifier, certs map[string]*Cert) []byte {
+	t.Helper()
+	attr := func(typ asn1.ObjectIdentifier, value []byte) attribute {
+		octets, err := asn1.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return attribute{Type: typ, Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: octets}}
+	}
+	ctl := certificateTrustList{
+		SubjectUsage:     []asn1.ObjectIdentifier{usage},
+		SequenceNumber:   big.NewInt(1),
+		CTLThisUpdate:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		SubjectAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}},
+	}
+	for name, cert := range certs {
+		thumbprint := sha1.Sum(cert.Raw)
+		wname := []byte{}
+		for _, c := range utf16.Encode([]rune(name + "\x00")) {
+			wname = append(wname, byte(c), byte(c>>8))
+		}
+		ctl.Subjects = append(ctl.Subjects, subject{
+			Thumbprint: thumbprint[:],
+			Attributes: []attribute{attr(szOID_CERT_FRIENDLY_NAME_PROP_ID, wname)},
+		})
+	}
+	content, err := asn1.Marshal(ctl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := protocol.NewSignedData(protocol.EncapsulatedContentInfo{
+		EContentType: szOID_CTL,
+		EContent:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := sd.ContentInfoDER()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestMicrosoftCTL_disallowed(t *testing.T) {
+	trusted := newValidCert(t, "Trusted Root")
+	revoked := newValidCert(t, "Revoked Root")
+
+	if _, _, err := parseDisallowed(makeSTL(t, szOID_ROOT_LIST_SIGNER, nil)); err == nil {
+		t.Errorf("parseDisallowed() of authroot.stl, want error")
+	}
+	removed, thumbprints, err := parseDisallowed(makeSTL(t, szOID_DISALLOWED_LIST, map[string]*Cert{"Revoked Root": revoked}))
+	if err != nil {
+		t.Fatalf("parseDisallowed() error = %v", err)
+	}
+	if len(removed) != 0 || len(thumbprints) != 1 {
+		t.Fatalf("parseDisallowed() = %v, %v, want one thumbprint", removed, thumbprints)
+	}
+
+	ctl := &MicrosoftCTL{CTL: newTestCTL([]*Cert{trusted, revoked}, nil)}
+	ctl.Disallowed = thumbprints
+	ret := ctl.Verify([]*Cert{trusted, revoked}, Entrys{})
+	if len(ret.TrustedCerts) != 1 || len(ret.RemovedCerts) != 1 || ret.RemovedCerts[0] != revoked {
+		t.Errorf("MicrosoftCTL.Verify() trusted = %d, removed = %d, want Revoked Root removed", len(ret.TrustedCerts), len(ret.RemovedCerts))
+	}
+	if e := ret.entries[revoked.Checksum]; e.Name != "Revoked Root" || e.Source != SourceDisallowed {
+		t.Errorf("MicrosoftCTL.Verify() entry = %+v, want from %s", e, SourceDisallowed)
+	}
+	if len(ctl.Trusted) != 2 {
+		t.Errorf("MicrosoftCTL.Verify() modified the CTL")
+	}
+
+	ctl.Trusted["AA"] = Entry{Name: "New Root", Usages: []string{TrustWebsites}}
+	if ret := ctl.Verify([]*Cert{trusted}, Entrys{}); len(ret.MissingEntries) != 0 {
+		t.Errorf("MicrosoftCTL.Verify() missing = %v, want none, Windows downloads roots on demand", ret.MissingEntries)
+	}
+}
@@ -2,15 +2,24 @@ package ctl
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
 	"time"
 )
 
 const (
 	MozillaIncludedCACertificateReportCSV = "https://ccadb-public.secure.force.com/mozilla/IncludedCACertificateReportCSVFormat"
 	MozillaRemovedCACertificateReportCSV  = "https://ccadb-public.secure.force.com/mozilla/RemovedCACertificateReportCSVFormat"
+	MozillaBugURL                         = "https://bugzilla.mozilla.org/show_bug.cgi?id="
 )
 
+// bug number in the removal column, e.g. "1234567" or "Bug 1234567"
+var mozillaBugPattern = regexp.MustCompile(`^(?i:bug\s*)?(\d{5,})$`)
+
 type MozillaCTL struct {
 	*CTL             `yaml:",inline"`
 	URLIncluded      string `yaml:"url_included,omitempty"`
@@ -29,9 +38,19 @@ func NewMozillaCTL() *MozillaCTL {
 	}
 }
 
+// Name of the source, see CTLSource
+func (ctl *MozillaCTL) Name() string { return MOZILLA }
+
+// Vendor name shown in reports, see CTLSource
+func (ctl *MozillaCTL) Vendor() string { return "Mozilla" }
+
+// TrustList returns the fetched CTL, see CTLSource
+func (ctl *MozillaCTL) TrustList() *CTL { return ctl.CTL }
+
 // Verify that the specified certificate is included in the CTL or has been removed
 func (ctl *MozillaCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
 	ret := VerifyResult{
+		Vendor:       ctl.Vendor(),
 		Total:        len(certs),
 		TrustedCerts: []*Cert{},
 		AllowedCerts: []*Cert{},
@@ -46,31 +65,36 @@ func (ctl *MozillaCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult
 }
 
 // Fetch Mozilla's CA certificate report from https://www.ccadb.org
-func (ctl *MozillaCTL) Fetch() error {
+func (ctl *MozillaCTL) Fetch(ctx context.Context, cl *http.Client) error {
 	if ctl.CTL == nil {
 		ctl.CTL = NewCTL()
 	}
-
-	body, err := getBody(MozillaIncludedCACertificateReportCSV)
-	if err != nil {
-		return err
+	if ctl.legacy() {
+		ctl.ChecksumIncluded, ctl.ChecksumRemoved = "", ""
 	}
 
-	if err := ctl.parseIncludedCSV(body); err != nil {
+	var included, removed []byte
+	err := fetchConcurrently(
+		func() (err error) { included, err = getBody(ctx, cl, ctl.URLIncluded); return err },
+		func() (err error) { removed, err = getBody(ctx, cl, ctl.URLRemoved); return err },
+	)
+	if err != nil {
 		return err
 	}
 
-	body, err = getBody(MozillaRemovedCACertificateReportCSV)
-	if err != nil {
+	if err := ctl.parseIncludedCSV(included, warnf(cl)); err != nil {
 		return err
 	}
 
-	err = ctl.parseRemovedCSV(body)
+	err = ctl.parseRemovedCSV(removed)
 
 	return err
 }
 
-func (ctl *MozillaCTL) parseIncludedCSV(body []byte) error {
+// parseIncludedCSV reads the included roots. A root with an unreadable
+// constraint is kept with the rest of its constraint and reported to warnf,
+// one bad cell of the report must not fail the whole fetch.
+func (ctl *MozillaCTL) parseIncludedCSV(body []byte, warnf func(format string, args ...any)) error {
 	checksum := getChecksum(body)
 	if checksum == ctl.ChecksumIncluded { // no update
 		return nil
@@ -83,7 +107,16 @@ func (ctl *MozillaCTL) parseIncludedCSV(body []byte) error {
 	for _, v := range c {
 		name := v["Common Name or Certificate Name"]
 		sha256 := v["SHA-256 Fingerprint"]
-		ctl.Trusted[sha256] = name
+		constraint, err := parseMozillaConstraint(v)
+		if err != nil {
+			warnf("Mozilla CTL: %s (SHA256 %s): %v", name, sha256, err)
+		}
+		ctl.Trusted[sha256] = Entry{
+			Name:       name,
+			Usages:     splitList(v["Trust Bits"]),
+			Source:     SourceCCADB,
+			Constraint: constraint,
+		}
 	}
 	ctl.ChecksumIncluded = checksum
 	ctl.UpdatedAt = time.Now()
@@ -105,10 +138,67 @@ func (ctl *MozillaCTL) parseRemovedCSV(body []byte) error {
 	for _, v := range c {
 		name := v["Root Certificate Name"]
 		sha256 := v["SHA-256 Fingerprint"]
-		ctl.Removed[sha256] = name
+		ctl.Removed[sha256] = parseMozillaRemoval(name, v["Removal Bug No. or Date"], v["Comments"])
 	}
 	ctl.ChecksumRemoved = checksum
 	ctl.UpdatedAt = time.Now()
 
 	return nil
 }
+
+// parseMozillaConstraint reads the EV policies and distrust dates of an
+// included root, it returns nil if the report has none of them. Unreadable
+// dates are left unset and returned as error with the rest of the constraint.
+func parseMozillaConstraint(v map[string]string) (*Constraint, error) {
+	c := Constraint{
+		EVPolicyOIDs: []string{},
+	}
+	for _, oid := range splitList(v["EV Policy OID(s)"]) {
+		if oid != "Not EV" {
+			c.EVPolicyOIDs = append(c.EVPolicyOIDs, oid)
+		}
+	}
+	var errs []error
+	if date := strings.TrimSpace(v["Distrust for TLS After Date"]); date != "" {
+		t, err := parseDate(date)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("parse distrust for TLS after date: %w", err))
+		}
+		c.DistrustTLSAfter = t
+	}
+	if date := strings.TrimSpace(v["Distrust for S/MIME After Date"]); date != "" {
+		t, err := parseDate(date)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("parse distrust for S/MIME after date: %w", err))
+		}
+		c.DistrustEmailAfter = t
+	}
+	err := errors.Join(errs...)
+	if len(c.EVPolicyOIDs) == 0 && c.DistrustTLSAfter.IsZero() && c.DistrustEmailAfter.IsZero() {
+		return nil, err
+	}
+	return &c, err
+}
+
+// parseMozillaRemoval reads the "Removal Bug No. or Date" column of a removed
+// root, which holds a Bugzilla bug number, a date or free text.
+func parseMozillaRemoval(name, removal, comments string) Entry {
+	entry := Entry{
+		Name:   name,
+		Status: "Removed",
+		Reason: strings.TrimSpace(comments),
+		Source: SourceCCADB,
+	}
+	removal = strings.TrimSpace(removal)
+	if m := mozillaBugPattern.FindStringSubmatch(removal); m != nil {
+		entry.URL = MozillaBugURL + m[1]
+		if entry.Reason == "" {
+			entry.Reason = "Bug " + m[1]
+		}
+	} else if date, err := parseDate(removal); err == nil {
+		entry.RemovedAt = date
+	} else if entry.Reason == "" {
+		entry.Reason = removal
+	}
+	return entry
+}
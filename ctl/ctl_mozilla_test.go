@@ -1,10 +1,21 @@
 package ctl
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
 
 func TestMozillaCTL_Fetch(t *testing.T) {
 	ctl := NewMozillaCTL()
-	err := ctl.Fetch()
+	err := ctl.Fetch(context.Background(), nil)
 	if err != nil {
 		t.Errorf("MozillaCTL.Fetch() error = %v", err)
 	}
@@ -12,3 +23,142 @@ func TestMozillaCTL_Fetch(t *testing.T) {
 		t.Errorf("MozillaCTL.Fetch() error = %v", "no trusted certs, may be parse error")
 	}
 }
+
+func TestMozillaCTL_Fetch_mirror(t *testing.T) {
+	dir := t.TempDir()
+	included := `"Common Name or Certificate Name","SHA-256 Fingerprint","Trust Bits"` + "\n" +
+		`"Included Root","AAAA","Websites"` + "\n"
+	removed := `"Root Certificate Name","SHA-256 Fingerprint","Removal Bug No. or Date","Comments"` + "\n" +
+		`"Removed Root","BBBB","1234567",""` + "\n"
+	for name, data := range map[string]string{"included.csv": included, "removed.csv": removed} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctl := NewMozillaCTL()
+	ctl.URLIncluded = (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "included.csv"))}).String()
+	ctl.URLRemoved = (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "removed.csv"))}).String()
+	if err := ctl.Fetch(context.Background(), nil); err != nil {
+		t.Fatalf("MozillaCTL.Fetch() error = %v", err)
+	}
+	if ctl.Trusted["AAAA"].Name != "Included Root" || ctl.Removed["BBBB"].Name != "Removed Root" {
+		t.Errorf("MozillaCTL.Fetch() trusted = %v, removed = %v", ctl.Trusted, ctl.Removed)
+	}
+
+	ctl.URLRemoved = "file:///nonexistent/removed.csv"
+	if err := ctl.Fetch(context.Background(), nil); err == nil {
+		t.Errorf("MozillaCTL.Fetch() of a missing mirror file, want error")
+	}
+}
+
+func TestMozillaCTL_Fetch_legacy(t *testing.T) {
+	dir := t.TempDir()
+	included := `"Common Name or Certificate Name","SHA-256 Fingerprint","Trust Bits"` + "\n" +
+		`"Included Root","AAAA","Websites"` + "\n"
+	removed := `"Root Certificate Name","SHA-256 Fingerprint","Removal Bug No. or Date","Comments"` + "\n" +
+		`"Removed Root","BBBB","1234567",""` + "\n"
+	for name, data := range map[string]string{"included.csv": included, "removed.csv": removed} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ctlcheck.yml of an older version: the sources have not changed since,
+	// but the entries only have their names
+	old := fmt.Sprintf(`url_included: %s
+checksum_included: %s
+url_removed: %s
+checksum_removed: %s
+trusted:
+  AAAA: Included Root
+removed:
+  BBBB: Removed Root
+`,
+		(&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "included.csv"))}).String(),
+		getChecksum([]byte(included)),
+		(&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "removed.csv"))}).String(),
+		getChecksum([]byte(removed)),
+	)
+	ctl := NewMozillaCTL()
+	if err := yaml.Unmarshal([]byte(old), ctl); err != nil {
+		t.Fatal(err)
+	}
+	if !ctl.legacy() {
+		t.Fatalf("CTL.legacy() = false for entries of the old format")
+	}
+
+	if err := ctl.Fetch(context.Background(), nil); err != nil {
+		t.Fatalf("MozillaCTL.Fetch() error = %v", err)
+	}
+	if got := ctl.Trusted["AAAA"]; got.Source != SourceCCADB || !got.Trusts(TrustWebsites) || got.Trusts(TrustEmail) {
+		t.Errorf("MozillaCTL.Fetch() trusted = %+v, want the trust bits of the report", got)
+	}
+	if got := ctl.Removed["BBBB"]; got.Source != SourceCCADB || got.URL == "" {
+		t.Errorf("MozillaCTL.Fetch() removed = %+v, want the removal of the report", got)
+	}
+	if ctl.legacy() {
+		t.Errorf("CTL.legacy() = true after the sources were parsed again")
+	}
+}
+
+func TestMozillaCTL_parseIncludedCSV(t *testing.T) {
+	web := newValidCert(t, "Web Root")
+	email := newValidCert(t, "Email Root")
+	distrusted := newValidCert(t, "Distrusted Root")
+	garbled := newValidCert(t, "Garbled Root")
+
+	csv := `"Common Name or Certificate Name","SHA-256 Fingerprint","Trust Bits","Distrust for TLS After Date","Distrust for S/MIME After Date","EV Policy OID(s)"` + "\n" +
+		fmt.Sprintf(`"Web Root","%s","Email;Websites","","","2.23.140.1.1"`, web.Checksum) + "\n" +
+		fmt.Sprintf(`"Email Root","%s","Email","","","Not EV"`, email.Checksum) + "\n" +
+		fmt.Sprintf(`"Distrusted Root","%s","Email;Websites","2022.11.30","2022.11.30","Not EV"`, distrusted.Checksum) + "\n" +
+		fmt.Sprintf(`"Garbled Root","%s","Websites","end of 2024","","2.23.140.1.1"`, garbled.Checksum) + "\n"
+
+	ctl := NewMozillaCTL()
+	var warnings []string
+	warnf := func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) }
+	if err := ctl.parseIncludedCSV([]byte(csv), warnf); err != nil {
+		t.Fatalf("MozillaCTL.parseIncludedCSV() error = %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Garbled Root") {
+		t.Errorf("MozillaCTL.parseIncludedCSV() warnings = %q, want one for Garbled Root", warnings)
+	}
+	if e, ok := ctl.Trusted[garbled.Checksum]; !ok || e.Constraint == nil || len(e.Constraint.EVPolicyOIDs) != 1 || !e.Constraint.DistrustTLSAfter.IsZero() {
+		t.Errorf("Trusted[Garbled Root] = %+v, want kept with its EV policy", e)
+	}
+	if e := ctl.Trusted[web.Checksum]; e.Constraint == nil || len(e.Constraint.EVPolicyOIDs) != 1 || e.PartiallyDistrusted() {
+		t.Errorf("Trusted[Web Root] = %+v, want EV and fully trusted", e)
+	}
+	if c := ctl.Trusted[distrusted.Checksum].Constraint; c == nil || c.DistrustTLSAfter.Format("2006-01-02") != "2022-11-30" {
+		t.Errorf("Trusted[Distrusted Root].Constraint = %+v, want distrust for TLS after 2022-11-30", c)
+	}
+
+	ret := ctl.Verify([]*Cert{web, email, distrusted}, Entrys{})
+	if len(ret.TrustedCerts) != 1 || ret.TrustedCerts[0] != web {
+		t.Errorf("MozillaCTL.Verify() trusted = %d, want only Web Root", len(ret.TrustedCerts))
+	}
+	if len(ret.PartialCerts) != 2 {
+		t.Errorf("MozillaCTL.Verify() partial = %d, want 2", len(ret.PartialCerts))
+	}
+	if d := ret.certDetails(email); len(d) != 1 || d[0].Value != "trusted for Email only" {
+		t.Errorf("VerifyResult.certDetails(Email Root) = %v, want %q", d, "trusted for Email only")
+	}
+}
+
+func Test_parseMozillaRemoval(t *testing.T) {
+	tests := []struct {
+		removal string
+		want    Entry
+	}{
+		{"1234567", Entry{Name: "Root", Status: "Removed", Reason: "Bug 1234567", URL: MozillaBugURL + "1234567", Source: SourceCCADB}},
+		{"Bug 1234567", Entry{Name: "Root", Status: "Removed", Reason: "Bug 1234567", URL: MozillaBugURL + "1234567", Source: SourceCCADB}},
+		{"2019.07.01", Entry{Name: "Root", Status: "Removed", RemovedAt: time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC), Source: SourceCCADB}},
+		{"NSS 3.53", Entry{Name: "Root", Status: "Removed", Reason: "NSS 3.53", Source: SourceCCADB}},
+	}
+	for _, tt := range tests {
+		got := parseMozillaRemoval("Root", tt.removal, "")
+		if got.Reason != tt.want.Reason || got.URL != tt.want.URL || !got.RemovedAt.Equal(tt.want.RemovedAt) || got.Status != tt.want.Status {
+			t.Errorf("parseMozillaRemoval(%q) = %+v, want %+v", tt.removal, got, tt.want)
+		}
+	}
+}
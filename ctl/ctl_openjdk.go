@@ -0,0 +1,219 @@
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// GitHub API listing of the root certificates shipped in the OpenJDK cacerts
+	OpenJDKCacertsURL = "https://api.github.com/repos/openjdk/jdk/contents/src/java.base/share/data/cacerts"
+	// Browsable history of the cacerts directory, the source of truth for removals
+	OpenJDKCacertsHistory = "https://github.com/openjdk/jdk/commits/master/src/java.base/share/data/cacerts"
+)
+
+// cacerts directories in a jdk checkout, the current one first
+var openJDKCacertsDirs = []string{
+	"src/java.base/share/data/cacerts",
+	"make/data/cacerts",
+}
+
+// OpenJDKCTL is the list of root certificates in the upstream OpenJDK cacerts.
+// OpenJDK does not publish removed roots, so only roots that disappear from
+// the snapshot between two fetches saved in ctlcheck.yml are moved to Removed,
+// without a removal date. Roots removed upstream before the first fetch are
+// reported as unknown, their removal is only found in OpenJDKCacertsHistory.
+type OpenJDKCTL struct {
+	*CTL     `yaml:",inline"`
+	URL      string `yaml:"url,omitempty"`
+	Path     string `yaml:"path,omitempty"`
+	Checksum string `yaml:"checksum,omitempty"`
+	// StorePass is the password of a cacerts keystore at Path, it is not
+	// saved in ctlcheck.yml
+	StorePass string `yaml:"-"`
+}
+
+func NewOpenJDKCTL() *OpenJDKCTL {
+	return &OpenJDKCTL{
+		CTL:       NewCTL(),
+		URL:       OpenJDKCacertsURL,
+		Path:      "",
+		Checksum:  "",
+		StorePass: DefaultKeyStorePassword,
+	}
+}
+
+// Name of the source, see CTLSource
+func (ctl *OpenJDKCTL) Name() string { return OPENJDK }
+
+// Vendor name shown in reports, see CTLSource
+func (ctl *OpenJDKCTL) Vendor() string { return "OpenJDK" }
+
+// TrustList returns the fetched CTL, see CTLSource
+func (ctl *OpenJDKCTL) TrustList() *CTL { return ctl.CTL }
+
+// Verify that the specified certificate is included in the CTL or has been removed
+func (ctl *OpenJDKCTL) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
+	ret := VerifyResult{
+		Vendor:       ctl.Vendor(),
+		Total:        len(certs),
+		TrustedCerts: []*Cert{},
+		AllowedCerts: []*Cert{},
+		allowedDesc:  "Allow by yourself in the config file.\n",
+		RemovedCerts: []*Cert{},
+		removedDesc:  "Removed from the OpenJDK cacerts since an earlier fetch, find the reason in: \n" + OpenJDKCacertsHistory + "\n",
+		UnknownCerts: []*Cert{},
+		unknownDesc:  "Not in the OpenJDK cacerts. Roots removed before the first saved fetch are reported here, find them in: \n" + OpenJDKCacertsHistory + "\n",
+	}
+	ctl.verify(certs, allowedCerts, &ret)
+	return &ret
+}
+
+// Fetch the root certificates of the OpenJDK cacerts, from Path if set,
+// otherwise from the jdk repository on GitHub.
+//
+// Path may be a jdk checkout, its cacerts directory, a file of PEM encoded
+// certificates or a cacerts keystore.
+func (ctl *OpenJDKCTL) Fetch(ctx context.Context, cl *http.Client) error {
+	if ctl.CTL == nil {
+		ctl.CTL = NewCTL()
+	}
+	if ctl.legacy() {
+		ctl.Checksum = ""
+	}
+	if ctl.Path != "" {
+		return ctl.load(ctl.Path)
+	}
+
+	body, err := getBody(ctx, cl, ctl.URL)
+	if err != nil {
+		return err
+	}
+	checksum := getChecksum(body)
+	if checksum == ctl.Checksum { // no update
+		return nil
+	}
+	var files []struct {
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		DownloadURL string `json:"download_url"`
+	}
+	if err := json.Unmarshal(body, &files); err != nil {
+		return fmt.Errorf("read cacerts listing err: %w", err)
+	}
+	fetches := []func() error{}
+	data := make([][]byte, len(files))
+	for i, f := range files {
+		if f.Type != "file" || f.DownloadURL == "" {
+			continue
+		}
+		i, url := i, f.DownloadURL
+		fetches = append(fetches, func() (err error) { data[i], err = getBody(ctx, cl, url); return err })
+	}
+	if err := fetchConcurrently(fetches...); err != nil {
+		return err
+	}
+	store := NewCertStore()
+	for _, pem := range data {
+		store.AppendCertsFromPEM(pem)
+	}
+	if len(store.Certs) == 0 {
+		return fmt.Errorf("no certificates found in %s", ctl.URL)
+	}
+	ctl.update(store)
+	ctl.Checksum = checksum
+	return nil
+}
+
+// load the snapshot from a local path
+func (ctl *OpenJDKCTL) load(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	store := NewCertStore()
+	h := sha256.New()
+	if fi.IsDir() {
+		dir := path
+		for _, d := range openJDKCacertsDirs {
+			if fi, err := os.Stat(filepath.Join(path, d)); err == nil && fi.IsDir() {
+				dir = filepath.Join(path, d)
+				break
+			}
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return err
+			}
+			h.Write(data)
+			store.AppendCertsFromPEM(data)
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write(data)
+		if bytes.Contains(data, []byte("-----BEGIN ")) {
+			store.AppendCertsFromPEM(data)
+		} else if err := store.AppendCertsFromKeyStore(data, ctl.StorePass); err != nil {
+			return err
+		}
+	}
+	if len(store.Certs) == 0 {
+		return fmt.Errorf("no certificates found in %s", path)
+	}
+
+	checksum := getChecksum(h.Sum(nil))
+	if checksum == ctl.Checksum { // no update
+		return nil
+	}
+	ctl.update(store)
+	ctl.Checksum = checksum
+	return nil
+}
+
+// update replaces the trusted entries with the snapshot, moving entries that
+// are no longer included to Removed. Their removal date is unknown, the
+// snapshot may be the first one fetched after it.
+func (ctl *OpenJDKCTL) update(snapshot *CertStore) {
+	trusted := Entrys{}
+	for _, cert := range snapshot.Certs {
+		trusted[cert.Checksum] = Entry{Name: pkixName(cert.Subject), Source: SourceCacerts}
+	}
+	if ctl.Removed == nil {
+		ctl.Removed = Entrys{}
+	}
+	for k, v := range ctl.Trusted {
+		if _, ok := trusted[k]; !ok {
+			ctl.Removed[k] = Entry{
+				Name:   v.Name,
+				Status: "Removed",
+				Reason: "no longer included in the OpenJDK cacerts",
+				URL:    OpenJDKCacertsHistory,
+				Source: SourceCacerts,
+			}
+		}
+	}
+	for k := range trusted {
+		delete(ctl.Removed, k)
+	}
+	ctl.Trusted = trusted
+	ctl.UpdatedAt = time.Now()
+}
@@ -0,0 +1,74 @@
+package ctl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenJDKCTL_Fetch(t *testing.T) {
+	kept := newValidCert(t, "Kept Root")
+	dropped := newValidCert(t, "Dropped Root")
+	added := newValidCert(t, "Added Root")
+
+	checkout := t.TempDir()
+	dir := filepath.Join(checkout, openJDKCacertsDirs[0])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, c *Cert) {
+		if err := os.WriteFile(filepath.Join(dir, name), append([]byte("Owner: CN="+c.Subject.CommonName+"\n"), pemCert(c)...), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("keptroot", kept)
+	write("droppedroot", dropped)
+
+	ctl := NewOpenJDKCTL()
+	ctl.Path = checkout
+	if err := ctl.Fetch(context.Background(), nil); err != nil {
+		t.Fatalf("OpenJDKCTL.Fetch() error = %v", err)
+	}
+	if len(ctl.Trusted) != 2 || len(ctl.Removed) != 0 {
+		t.Fatalf("OpenJDKCTL.Fetch() trusted = %d, removed = %d, want 2, 0", len(ctl.Trusted), len(ctl.Removed))
+	}
+
+	// next snapshot: one root removed, one added
+	if err := os.Remove(filepath.Join(dir, "droppedroot")); err != nil {
+		t.Fatal(err)
+	}
+	write("addedroot", added)
+	if err := ctl.Fetch(context.Background(), nil); err != nil {
+		t.Fatalf("OpenJDKCTL.Fetch() error = %v", err)
+	}
+	ret := ctl.Verify([]*Cert{kept, dropped, added}, Entrys{})
+	if len(ret.TrustedCerts) != 2 || len(ret.RemovedCerts) != 1 || ret.RemovedCerts[0] != dropped {
+		t.Errorf("OpenJDKCTL.Verify() trusted = %d, removed = %d, want 2, 1 (%s)", len(ret.TrustedCerts), len(ret.RemovedCerts), dropped.Subject.CommonName)
+	}
+	if e := ctl.Removed[dropped.Checksum]; !e.RemovedAt.IsZero() {
+		t.Errorf("OpenJDKCTL.Fetch() removed on %v, want no date, only the fetch is known", e.RemovedAt)
+	}
+}
+
+func TestOpenJDKCTL_Fetch_keystore(t *testing.T) {
+	key := newValidCert(t, "Server")
+	root := newValidCert(t, "Keystore Root")
+	path := filepath.Join(t.TempDir(), "cacerts")
+	if err := os.WriteFile(path, makeJKS("secret", key, root), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctl := NewOpenJDKCTL()
+	ctl.Path = path
+	if err := ctl.Fetch(context.Background(), nil); err == nil {
+		t.Errorf("OpenJDKCTL.Fetch() with the default password, want error")
+	}
+	ctl.StorePass = "secret"
+	if err := ctl.Fetch(context.Background(), nil); err != nil {
+		t.Fatalf("OpenJDKCTL.Fetch() error = %v", err)
+	}
+	if _, ok := ctl.Trusted[root.Checksum]; !ok || len(ctl.Trusted) != 1 {
+		t.Errorf("OpenJDKCTL.Fetch() trusted = %v, want only %s", ctl.Trusted, root.Subject.CommonName)
+	}
+}
@@ -0,0 +1,145 @@
+package ctl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCert returns a self-signed root certificate valid from notBefore to notAfter.
+func newTestCert(t *testing.T, commonName string, notBefore, notAfter time.Time) *Cert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return &Cert{Certificate: cert, Checksum: getChecksum(der)}
+}
+
+// newValidCert returns a test certificate that is valid for an hour before and after now
+func newValidCert(t *testing.T, commonName string) *Cert {
+	t.Helper()
+	now := time.Now()
+	return newTestCert(t, commonName, now.Add(-time.Hour), now.Add(time.Hour))
+}
+
+// newTestCTL returns a CTL that trusts the trusted and removed the removed
+// certificates, with their common name as the name of the entries
+func newTestCTL(trusted, removed []*Cert) *CTL {
+	ctl := NewCTL()
+	for _, cert := range trusted {
+		ctl.Trusted[cert.Checksum] = Entry{Name: cert.Subject.CommonName}
+	}
+	for _, cert := range removed {
+		ctl.Removed[cert.Checksum] = Entry{Name: cert.Subject.CommonName}
+	}
+	return ctl
+}
+
+func TestCTL_verify(t *testing.T) {
+	trusted := newValidCert(t, "Trusted Root")
+	allowed := newValidCert(t, "Allowed Root")
+	removed := newValidCert(t, "Removed Root")
+	unknown := newValidCert(t, "Unknown Root")
+
+	ctl := &MozillaCTL{CTL: newTestCTL([]*Cert{trusted}, []*Cert{removed})}
+	ret := ctl.Verify([]*Cert{trusted, allowed, removed, unknown}, Entrys{allowed.Checksum: {Name: "allowed"}})
+
+	if ret.Total != 4 {
+		t.Errorf("Verify().Total = %d, want 4", ret.Total)
+	}
+	for name, certs := range map[string][]*Cert{
+		"TrustedCerts": ret.TrustedCerts,
+		"AllowedCerts": ret.AllowedCerts,
+		"RemovedCerts": ret.RemovedCerts,
+		"UnknownCerts": ret.UnknownCerts,
+	} {
+		if len(certs) != 1 {
+			t.Errorf("Verify().%s has %d certs, want 1", name, len(certs))
+		}
+	}
+}
+
+func TestVerifyResult_ConsoleReport(t *testing.T) {
+	removed := newValidCert(t, "Removed Root")
+
+	ctl := NewMozillaCTL()
+	ctl.Removed[removed.Checksum] = parseMozillaRemoval("Removed Root", "1234567", "")
+	output := ctl.Verify([]*Cert{removed}, Entrys{}).ConsoleReport()
+
+	for _, want := range []string{"Reason:     Bug 1234567", "Link:       " + MozillaBugURL + "1234567"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("ConsoleReport() = %s, want %q", output, want)
+		}
+	}
+}
+
+func TestVerifyResult_Expiring(t *testing.T) {
+	now := time.Now()
+	expired := newTestCert(t, "Expired Root", now.Add(-48*time.Hour), now.Add(-time.Hour))
+	expiring := newTestCert(t, "Expiring Root", now.Add(-time.Hour), now.Add(10*24*time.Hour))
+	valid := newTestCert(t, "Valid Root", now.Add(-time.Hour), now.Add(100*24*time.Hour))
+	future := newTestCert(t, "Future Root", now.Add(24*time.Hour), now.Add(100*24*time.Hour))
+
+	ctl := &MozillaCTL{CTL: newTestCTL([]*Cert{valid}, nil)}
+	ret := ctl.Verify([]*Cert{expired, expiring, valid, future}, Entrys{expiring.Checksum: {Name: "expiring"}})
+
+	if ret.ExpiryWindow != DefaultExpiryWindow {
+		t.Errorf("Verify().ExpiryWindow = %v, want %v", ret.ExpiryWindow, DefaultExpiryWindow)
+	}
+	if got := ret.Expired(now); len(got) != 1 || got[0] != expired {
+		t.Errorf("Expired() = %v, want the expired root", got)
+	}
+	if got := ret.Expiring(now, 30*24*time.Hour); len(got) != 1 || got[0] != expiring {
+		t.Errorf("Expiring() = %v, want the allowed root expiring in 10 days", got)
+	}
+	if got := ret.Expiring(now, 0); len(got) != 0 {
+		t.Errorf("Expiring() without a window = %v, want none", got)
+	}
+	if got := ret.NotYetValid(now); len(got) != 1 || got[0] != future {
+		t.Errorf("NotYetValid() = %v, want the root valid from tomorrow", got)
+	}
+	for cert, want := range map[*Cert]string{expired: expiryExpired, expiring: expiryExpiring, valid: "", future: expiryNotYetValid} {
+		if got := ret.expiry(cert, now); got != want {
+			t.Errorf("expiry(%s) = %q, want %q", cert.Subject.CommonName, got, want)
+		}
+	}
+
+	ret.ExpiryWindow = 5 * 24 * time.Hour
+	output := ret.ConsoleReport()
+	for _, want := range []string{"Expired Certificates", "Not Yet Valid Certificates", "Class:      Unknown"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("ConsoleReport() = %s, want %q", output, want)
+		}
+	}
+	if strings.Contains(output, "Certificates Expiring") {
+		t.Errorf("ConsoleReport() = %s, want no expiring certificates within 5 days", output)
+	}
+	ret.ExpiryWindow = DefaultExpiryWindow
+	if output := ret.ConsoleReport(); !strings.Contains(output, "Certificates Expiring within 90 Days") || !strings.Contains(output, "Class:      Allowed") {
+		t.Errorf("ConsoleReport() = %s, want the allowed root expiring within 90 days", output)
+	}
+}
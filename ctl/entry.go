@@ -0,0 +1,134 @@
+package ctl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of entries
+const (
+	SourceCCADB      = "ccadb"
+	SourceAuthroot   = "authroot.stl"
+	SourceDisallowed = "disallowedcert.stl"
+	SourceBuiltIn    = "built-in"
+	SourceRootStore  = "root_store.textproto"
+	SourceCacerts    = "cacerts"
+	SourceAppleTable = "apple"
+	SourceInternal   = "internal"
+)
+
+// Entry is a root certificate in a vendor's CTL, or in the allow list.
+type Entry struct {
+	Name string `yaml:"name" json:"name,omitempty"`
+	// Status is the vendor's status of the root, e.g. the Microsoft Status
+	Status string `yaml:"status,omitempty" json:"status,omitempty"`
+	// Reason of the removal, e.g. a bug number or a deprecation notice
+	Reason    string    `yaml:"reason,omitempty" json:"reason,omitempty"`
+	RemovedAt time.Time `yaml:"removed_at,omitempty" json:"removed_at,omitempty"`
+	// URL of the reference explaining the status, e.g. the removal bug
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Usages the root is trusted for, e.g. Mozilla trust bits or Microsoft EKUs,
+	// empty if unknown
+	Usages  []string `yaml:"usages,omitempty" json:"usages,omitempty"`
+	KeyType string   `yaml:"key_type,omitempty" json:"key_type,omitempty"`
+	// Source the entry was read from, e.g. SourceCCADB
+	Source     string      `yaml:"source,omitempty" json:"source,omitempty"`
+	Constraint *Constraint `yaml:"constraint,omitempty" json:"constraint,omitempty"`
+	// Owner and Expires of roots in an internal CTL, the root is no longer
+	// trusted after Expires
+	Owner   string    `yaml:"owner,omitempty" json:"owner,omitempty"`
+	Expires time.Time `yaml:"expires,omitempty" json:"expires,omitempty"`
+}
+
+// Entrys maps from sum256(cert.Raw) to the entry of the certificate.
+type Entrys map[string]Entry
+
+// plainEntry has the fields of Entry without its YAML methods
+type plainEntry Entry
+
+// UnmarshalYAML also accepts the name alone, as written by earlier versions
+// and in hand-written allow lists.
+func (e *Entry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*e = Entry{}
+		return value.Decode(&e.Name)
+	}
+	return value.Decode((*plainEntry)(e))
+}
+
+// MarshalYAML writes entries that only have a name as the name alone.
+func (e Entry) MarshalYAML() (interface{}, error) {
+	if e.isNameOnly() {
+		return e.Name, nil
+	}
+	return plainEntry(e), nil
+}
+
+func (e Entry) isNameOnly() bool {
+	return e.Status == "" && e.Reason == "" && e.RemovedAt.IsZero() && e.URL == "" &&
+		len(e.Usages) == 0 && e.KeyType == "" && e.Source == "" && e.Constraint == nil &&
+		e.Owner == "" && e.Expires.IsZero()
+}
+
+// Trusts reports whether the root is trusted for the purpose, e.g.
+// TrustWebsites. Roots without known usages are trusted for any purpose.
+func (e Entry) Trusts(purpose string) bool {
+	if len(e.Usages) == 0 {
+		return true
+	}
+	for _, usage := range e.Usages {
+		if usage == purpose || usageAliases[usage] == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// PartiallyDistrusted reports whether the root is not trusted for websites,
+// or is distrusted for certificates issued after a date.
+func (e Entry) PartiallyDistrusted() bool {
+	return !e.Trusts(TrustWebsites) || (e.Constraint != nil && e.Constraint.distrustsAfter())
+}
+
+// distrustDetails explain why the root is partially distrusted
+func (e Entry) distrustDetails() []detail {
+	parts := []string{}
+	if !e.Trusts(TrustWebsites) {
+		parts = append(parts, fmt.Sprintf("trusted for %s only", strings.Join(e.Usages, ", ")))
+	}
+	if e.Constraint != nil && e.Constraint.distrustsAfter() {
+		parts = append(parts, e.Constraint.String())
+	}
+	ret := []detail{{Label: "Note", Value: strings.Join(parts, "; ")}}
+	if e.Owner != "" {
+		ret = append(ret, detail{Label: "Owner", Value: e.Owner})
+	}
+	if e.URL != "" {
+		ret = append(ret, detail{Label: "Link", Value: e.URL})
+	}
+	return ret
+}
+
+// removalDetails explain when and why the root was removed
+func (e Entry) removalDetails() []detail {
+	ret := []detail{}
+	if e.Status != "" && e.Status != "Removed" {
+		ret = append(ret, detail{Label: "Status", Value: e.Status})
+	}
+	if !e.RemovedAt.IsZero() {
+		ret = append(ret, detail{Label: "Removed on", Value: e.RemovedAt.Format("2006-01-02")})
+	}
+	if e.Reason != "" {
+		ret = append(ret, detail{Label: "Reason", Value: e.Reason})
+	}
+	if e.Owner != "" {
+		ret = append(ret, detail{Label: "Owner", Value: e.Owner})
+	}
+	if e.URL != "" {
+		ret = append(ret, detail{Label: "Link", Value: e.URL})
+	}
+	return ret
+}
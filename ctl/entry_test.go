@@ -0,0 +1,75 @@
+package ctl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestEntrys_YAML(t *testing.T) {
+	// written by earlier versions, and by hand in allow lists
+	old := `
+updated_at: 2023-01-02T03:04:05Z
+trusted:
+  AA00: Kept Root
+removed:
+  BB00: Old Root
+`
+	ctl := NewCTL()
+	if err := yaml.Unmarshal([]byte(old), ctl); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	if ctl.Trusted["AA00"].Name != "Kept Root" || ctl.Removed["BB00"].Name != "Old Root" {
+		t.Fatalf("yaml.Unmarshal() = %+v, want the names of the old format", ctl)
+	}
+
+	ctl.Removed["CC00"] = Entry{
+		Name:      "Removed Root",
+		Status:    "Removed",
+		Reason:    "Bug 1234567",
+		RemovedAt: time.Date(2022, 11, 30, 0, 0, 0, 0, time.UTC),
+		URL:       MozillaBugURL + "1234567",
+		Usages:    []string{TrustWebsites},
+		Source:    SourceCCADB,
+	}
+	data, err := yaml.Marshal(ctl)
+	if err != nil {
+		t.Fatalf("yaml.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), "AA00: Kept Root\n") {
+		t.Errorf("yaml.Marshal() = %s, want entries with a name only as a string", data)
+	}
+
+	got := NewCTL()
+	if err := yaml.Unmarshal(data, got); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	e := got.Removed["CC00"]
+	if e.Reason != "Bug 1234567" || !e.RemovedAt.Equal(ctl.Removed["CC00"].RemovedAt) || e.URL != ctl.Removed["CC00"].URL || len(e.Usages) != 1 {
+		t.Errorf("yaml round trip = %+v, want %+v", e, ctl.Removed["CC00"])
+	}
+}
+
+func TestEntry_PartiallyDistrusted(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  bool
+	}{
+		{"unknown usages", Entry{}, false},
+		{"websites", Entry{Usages: []string{TrustEmail, TrustWebsites}}, false},
+		{"server authentication", Entry{Usages: []string{"Server Authentication", "Code Signing"}}, false},
+		{"email only", Entry{Usages: []string{TrustEmail}}, true},
+		{"distrust after", Entry{Constraint: &Constraint{DistrustTLSAfter: time.Now()}}, true},
+		{"ev only", Entry{Constraint: &Constraint{EVPolicyOIDs: []string{"2.23.140.1.1"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.PartiallyDistrusted(); got != tt.want {
+				t.Errorf("Entry.PartiallyDistrusted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
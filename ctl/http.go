@@ -0,0 +1,139 @@
+package ctl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPOptions configures the client the CTLs are fetched with
+type HTTPOptions struct {
+	// Timeout of a request, including its retries and reading the body, 0
+	// for no timeout
+	Timeout time.Duration
+	// Retries of a request that failed with a network error, 429 or 5xx,
+	// waiting Backoff before the first and twice as long before each next one
+	Retries int
+	Backoff time.Duration
+	// Proxy is the URL of the proxy server, HTTPS_PROXY, HTTP_PROXY and
+	// NO_PROXY are used if empty
+	Proxy string
+	// CAFile is a CA bundle file of root CAs trusted in addition to the
+	// system root CAs for the fetch, e.g. of a TLS-inspecting proxy
+	CAFile string
+	// CacheDir is the directory the fetched payloads are cached in, "" to
+	// disable the cache, see cacheTransport
+	CacheDir string
+	// Warnf reports non-fatal problems, e.g. that a cached copy is used
+	// because a CTL source is unreachable, nil to discard them
+	Warnf func(format string, args ...any)
+}
+
+// DefaultHTTPOptions are the HTTPOptions used by ctlcheck unless changed,
+// caching in ctlcheck in the user cache directory ($XDG_CACHE_HOME or ~/.cache
+// on Linux)
+var DefaultHTTPOptions = HTTPOptions{
+	Timeout:  2 * time.Minute,
+	Retries:  2,
+	Backoff:  time.Second,
+	CacheDir: defaultCacheDir(),
+}
+
+// NewHTTPClient returns a client to be shared by the Fetch methods of all CTLs
+func NewHTTPClient(opts HTTPOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+		if proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("parse proxy URL: %q has no scheme or host", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if opts.CAFile != "" {
+		store := NewCertStore()
+		if err := store.LoadBundleFiles(opts.CAFile); err != nil {
+			return nil, fmt.Errorf("load CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, cert := range store.Certs {
+			pool.AddCert(cert.Certificate)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	warnf := opts.Warnf
+	if warnf == nil {
+		warnf = func(format string, args ...any) {}
+	}
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &cacheTransport{
+			base: &retryTransport{
+				base:    transport,
+				retries: opts.Retries,
+				backoff: opts.Backoff,
+			},
+			dir:   opts.CacheDir,
+			warnf: warnf,
+		},
+	}, nil
+}
+
+// warnf returns the Warnf of the HTTPOptions cl was created with, for the
+// warnings of the Fetch methods, or a function that discards them
+func warnf(cl *http.Client) func(format string, args ...any) {
+	if cl != nil {
+		if t, ok := cl.Transport.(*cacheTransport); ok {
+			return t.warnf
+		}
+	}
+	return func(format string, args ...any) {}
+}
+
+// retryTransport retries idempotent requests that failed temporarily
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := t.backoff
+	for attempt := 0; ; attempt++ {
+		res, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !retryable(req, res, err) {
+			return res, err
+		}
+		if res != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+			res.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if err != nil { // but not if the server is not trusted, that will not change
+		var certErr *tls.CertificateVerificationError
+		return req.Context().Err() == nil && !errors.As(err, &certErr)
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,81 @@
+package ctl
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClient_retries(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/down", hits < 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, "payload")
+		}
+	}))
+	defer srv.Close()
+
+	cl, err := NewHTTPClient(HTTPOptions{Timeout: 10 * time.Second, Retries: 2, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	got, err := getBody(context.Background(), cl, srv.URL)
+	if err != nil || string(got) != "payload" || hits != 3 {
+		t.Errorf("getBody() = %q, %v after %d requests, want %q after 3", got, err, hits, "payload")
+	}
+	hits = 0
+	if _, err := getBody(context.Background(), cl, srv.URL+"/missing"); err == nil || hits != 1 {
+		t.Errorf("getBody() of a missing file error = %v after %d requests, want error after 1", err, hits)
+	}
+	hits = 0
+	if _, err := getBody(context.Background(), cl, srv.URL+"/down"); err == nil || hits != 3 {
+		t.Errorf("getBody() of an unavailable server error = %v after %d requests, want error after 3", err, hits)
+	}
+}
+
+func TestNewHTTPClient_CAFile(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "payload")
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // the untrusted handshake
+	srv.StartTLS()
+	defer srv.Close()
+	cl, err := NewHTTPClient(HTTPOptions{Retries: 2, Backoff: time.Hour})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	if _, err := getBody(context.Background(), cl, srv.URL); err == nil { // fails at once, without retries
+		t.Errorf("getBody() of an untrusted server, want error")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cl, err = NewHTTPClient(HTTPOptions{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+	if got, err := getBody(context.Background(), cl, srv.URL); err != nil || string(got) != "payload" {
+		t.Errorf("getBody() with CA file = %q, %v, want %q", got, err, "payload")
+	}
+
+	if _, err := NewHTTPClient(HTTPOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Errorf("NewHTTPClient() with a missing CA file, want error")
+	}
+}
@@ -0,0 +1,473 @@
+package ctl
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	ociImageIndex      = "application/vnd.oci.image.index.v1+json"
+	dockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// whiteout files of the OCI image layer spec
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+
+	// files larger than this are never CA bundles
+	maxImageFileSize = 16 << 20
+)
+
+// imageCertPrefixes are the directories of an image whose files are kept,
+// the locations of the CA bundles and the usual targets of their symlinks.
+var imageCertPrefixes = []string{
+	"etc/ssl/",
+	"etc/pki/",
+	"etc/ca-certificates/",
+	"etc/openssl/",
+	"usr/share/ca-certificates/",
+	"usr/local/share/ca-certificates/",
+	"usr/share/pki/",
+	"usr/lib/ssl/",
+	"system/etc/security/",
+}
+
+// LoadImageRoots loads the CA bundle of a Linux distribution from a container
+// image, given as an OCI image layout directory or a `docker save` tarball.
+// The layers are applied in order, honouring whiteouts, and the bundle is
+// located like LoadSystemRoots does on Linux. No registry is accessed.
+func LoadImageRoots(image string) (*CertStore, error) {
+	blobs, err := openImage(image)
+	if err != nil {
+		return nil, err
+	}
+	defer blobs.Close()
+
+	layers, err := blobs.layers()
+	if err != nil {
+		return nil, fmt.Errorf("read image manifest: %w", err)
+	}
+
+	rootfs := newImageFS()
+	for _, layer := range layers {
+		r, err := blobs.open(layer)
+		if err != nil {
+			return nil, fmt.Errorf("open layer %s: %w", layer, err)
+		}
+		err = rootfs.applyLayer(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("apply layer %s: %w", layer, err)
+		}
+	}
+
+	roots := NewCertStore()
+	for _, file := range linuxCertFiles {
+		if data, ok := rootfs.readFile(file); ok {
+			roots.AppendCertsFromPEM(data)
+			break
+		}
+	}
+	for _, directory := range linuxCertDirectories {
+		for _, file := range rootfs.readDir(directory) {
+			if data, ok := rootfs.readFile(file); ok {
+				roots.AppendCertsFromPEM(data)
+			}
+		}
+	}
+	if len(roots.Certs) == 0 {
+		return nil, fmt.Errorf("no CA bundle found in image %s", image)
+	}
+	return roots, nil
+}
+
+// imageBlobs gives access to the files of an image layout or tarball
+type imageBlobs interface {
+	io.Closer
+	open(name string) (io.ReadCloser, error)
+	layers() ([]string, error)
+}
+
+func openImage(image string) (imageBlobs, error) {
+	fi, err := os.Stat(image)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &layoutDir{dir: image}, nil
+	}
+	return openTarball(image)
+}
+
+// layoutDir is an OCI image layout directory
+type layoutDir struct {
+	dir string
+}
+
+func (d *layoutDir) Close() error { return nil }
+
+func (d *layoutDir) open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
+
+func (d *layoutDir) layers() ([]string, error) {
+	return imageLayers(d)
+}
+
+// imageTarball is a `docker save` tarball (or a tarball of an OCI image layout)
+type imageTarball struct {
+	f     *os.File
+	files map[string]*io.SectionReader
+}
+
+func openTarball(name string) (*imageTarball, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	t := &imageTarball{f: f, files: map[string]*io.SectionReader{}}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("read image tarball: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// the tar reader does not buffer, so the file is positioned at the data
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		t.files[path.Clean(hdr.Name)] = io.NewSectionReader(f, offset, hdr.Size)
+	}
+	return t, nil
+}
+
+func (t *imageTarball) Close() error { return t.f.Close() }
+
+func (t *imageTarball) open(name string) (io.ReadCloser, error) {
+	sr, ok := t.files[path.Clean(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return io.NopCloser(io.NewSectionReader(sr, 0, sr.Size())), nil
+}
+
+func (t *imageTarball) layers() ([]string, error) {
+	if _, ok := t.files["manifest.json"]; !ok {
+		return imageLayers(t) // OCI image layout
+	}
+	// https://github.com/moby/moby/blob/master/image/spec/v1.2.md#combined-image-json--filesystem-changeset-format
+	var manifest []struct {
+		Config   string
+		RepoTags []string
+		Layers   []string
+	}
+	if err := readJSON(t, "manifest.json", &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest) == 0 {
+		return nil, fmt.Errorf("no image in manifest.json")
+	}
+	return manifest[0].Layers, nil
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+// imageLayers returns the layer blobs of the image in an OCI image layout,
+// https://github.com/opencontainers/image-spec/blob/main/image-layout.md
+func imageLayers(blobs imageBlobs) ([]string, error) {
+	var index struct {
+		Manifests []ociDescriptor `json:"manifests"`
+	}
+	if err := readJSON(blobs, "index.json", &index); err != nil {
+		return nil, err
+	}
+	for depth := 0; depth < 8; depth++ {
+		desc, err := selectManifest(index.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		if desc.MediaType == ociImageIndex || desc.MediaType == dockerManifestList {
+			index.Manifests = nil
+			if err := readJSON(blobs, blobPath(desc.Digest), &index); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var manifest struct {
+			Layers []ociDescriptor `json:"layers"`
+		}
+		if err := readJSON(blobs, blobPath(desc.Digest), &manifest); err != nil {
+			return nil, err
+		}
+		layers := []string{}
+		for _, layer := range manifest.Layers {
+			layers = append(layers, blobPath(layer.Digest))
+		}
+		return layers, nil
+	}
+	return nil, fmt.Errorf("image index nested too deeply")
+}
+
+// selectManifest prefers the manifest for the current architecture and skips
+// attestation manifests, whose platform is unknown.
+func selectManifest(manifests []ociDescriptor) (ociDescriptor, error) {
+	var ret *ociDescriptor
+	for i, m := range manifests {
+		if m.Platform == nil {
+			if ret == nil {
+				ret = &manifests[i]
+			}
+			continue
+		}
+		if m.Platform.OS == "unknown" {
+			continue
+		}
+		if m.Platform.Architecture == runtime.GOARCH {
+			return m, nil
+		}
+		if ret == nil {
+			ret = &manifests[i]
+		}
+	}
+	if ret == nil {
+		return ociDescriptor{}, fmt.Errorf("no image manifest found")
+	}
+	return *ret, nil
+}
+
+func blobPath(digest string) string {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	return path.Join("blobs", algorithm, hex)
+}
+
+func readJSON(blobs imageBlobs, name string, v any) error {
+	r, err := blobs.open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// imageFS is the part of an image's root filesystem that may hold CA bundles.
+// Paths are relative to the root, without leading slash.
+type imageFS struct {
+	files map[string][]byte
+	links map[string]string
+}
+
+func newImageFS() *imageFS {
+	return &imageFS{
+		files: map[string][]byte{},
+		links: map[string]string{},
+	}
+}
+
+// applyLayer applies a (possibly gzip compressed) layer tarball. Whiteouts
+// only hide files of the lower layers, so they are applied first.
+func (fs *imageFS) applyLayer(r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return errors.New("zstd compressed layers are not supported")
+	default:
+		r = br
+	}
+
+	layer := newImageFS()
+	var whiteouts, opaques []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		dir, base := path.Split(name)
+		switch {
+		case base == whiteoutOpaque:
+			opaques = append(opaques, strings.TrimSuffix(dir, "/"))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			whiteouts = append(whiteouts, dir+strings.TrimPrefix(base, whiteoutPrefix))
+			continue
+		}
+		if !hasImageCertPrefix(name) {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if hdr.Size > maxImageFileSize {
+				continue
+			}
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			layer.remove(name)
+			layer.files[name] = data
+		case tar.TypeSymlink:
+			layer.remove(name)
+			layer.links[name] = hdr.Linkname
+		case tar.TypeLink:
+			target := strings.TrimPrefix(path.Clean("/"+hdr.Linkname), "/")
+			data, ok := layer.files[target]
+			if !ok {
+				data, ok = fs.files[target]
+			}
+			if ok {
+				layer.remove(name)
+				layer.files[name] = data
+			}
+		}
+	}
+
+	for _, dir := range opaques {
+		fs.removeChildren(dir)
+	}
+	for _, name := range whiteouts {
+		fs.remove(name)
+	}
+	for name, data := range layer.files {
+		fs.remove(name)
+		fs.files[name] = data
+	}
+	for name, target := range layer.links {
+		fs.remove(name)
+		fs.links[name] = target
+	}
+	return nil
+}
+
+func hasImageCertPrefix(name string) bool {
+	for _, prefix := range imageCertPrefixes {
+		if strings.HasPrefix(name+"/", prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove removes name and, if it is a directory, everything below it
+func (fs *imageFS) remove(name string) {
+	delete(fs.files, name)
+	delete(fs.links, name)
+	fs.removeChildren(name)
+}
+
+func (fs *imageFS) removeChildren(dir string) {
+	prefix := dir + "/"
+	for name := range fs.files {
+		if strings.HasPrefix(name, prefix) {
+			delete(fs.files, name)
+		}
+	}
+	for name := range fs.links {
+		if strings.HasPrefix(name, prefix) {
+			delete(fs.links, name)
+		}
+	}
+}
+
+// resolve follows the symlinks in all components of name
+func (fs *imageFS) resolve(name string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	for hops := 0; hops < 40; hops++ {
+		parts := strings.Split(name, "/")
+		resolved := true
+		for i := range parts {
+			p := strings.Join(parts[:i+1], "/")
+			target, ok := fs.links[p]
+			if !ok {
+				continue
+			}
+			if !path.IsAbs(target) {
+				target = path.Join("/", path.Dir(p), target)
+			}
+			name = strings.TrimPrefix(path.Join(target, strings.Join(parts[i+1:], "/")), "/")
+			resolved = false
+			break
+		}
+		if resolved {
+			return name, true
+		}
+	}
+	return "", false // too many levels of symbolic links
+}
+
+func (fs *imageFS) readFile(name string) ([]byte, bool) {
+	name, ok := fs.resolve(name)
+	if !ok {
+		return nil, false
+	}
+	data, ok := fs.files[name]
+	return data, ok
+}
+
+// readDir returns the sorted paths of the files and symlinks directly in dir
+func (fs *imageFS) readDir(dir string) []string {
+	dir, ok := fs.resolve(dir)
+	if !ok {
+		return nil
+	}
+	prefix := dir + "/"
+	ret := []string{}
+	isChild := func(name string) bool {
+		rest, ok := strings.CutPrefix(name, prefix)
+		return ok && !strings.Contains(rest, "/")
+	}
+	for name := range fs.files {
+		if isChild(name) {
+			ret = append(ret, name)
+		}
+	}
+	for name := range fs.links {
+		if isChild(name) {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
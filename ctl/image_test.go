@@ -0,0 +1,144 @@
+package ctl
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	data     []byte
+	linkname string
+}
+
+func makeTar(t *testing.T, entries []tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}
+		if e.linkname != "" {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.linkname, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testImageLayers returns two layers: the base layer ships the bundle and a
+// stale root in /etc/ssl/certs, the second layer removes it with a whiteout
+// and adds a local root as a symlink.
+func testImageLayers(t *testing.T) (layers [][]byte, want []*Cert) {
+	bundled := newValidCert(t, "Bundled Root")
+	stale := newValidCert(t, "Stale Root")
+	local := newValidCert(t, "Local Root")
+
+	base := makeTar(t, []tarEntry{
+		{name: "etc/ssl/certs/ca-certificates.crt", data: pemCert(bundled)},
+		{name: "usr/share/ca-certificates/stale.crt", data: pemCert(stale)},
+		{name: "etc/ssl/certs/stale.pem", linkname: "/usr/share/ca-certificates/stale.crt"},
+		{name: "usr/bin/true", data: []byte("ignored")},
+	})
+	top := makeTar(t, []tarEntry{
+		{name: "etc/ssl/certs/.wh.stale.pem"},
+		{name: "usr/local/share/ca-certificates/local.crt", data: pemCert(local)},
+		{name: "etc/ssl/certs/local.pem", linkname: "../../../usr/local/share/ca-certificates/local.crt"},
+	})
+	return [][]byte{base, gzipData(t, top)}, []*Cert{bundled, local}
+}
+
+func checkImageRoots(t *testing.T, image string, want []*Cert) {
+	t.Helper()
+	roots, err := LoadImageRoots(image)
+	if err != nil {
+		t.Fatalf("LoadImageRoots() error = %v", err)
+	}
+	if len(roots.Certs) != len(want) {
+		t.Errorf("LoadImageRoots() loaded %d certs, want %d", len(roots.Certs), len(want))
+	}
+	for _, c := range want {
+		if !roots.contains(c.Certificate) {
+			t.Errorf("LoadImageRoots() misses %s", c.Subject.CommonName)
+		}
+	}
+}
+
+func TestLoadImageRoots_dockerSave(t *testing.T) {
+	layers, want := testImageLayers(t)
+	manifest, _ := json.Marshal([]map[string]any{{
+		"Config":   "config.json",
+		"RepoTags": []string{"test:latest"},
+		"Layers":   []string{"base/layer.tar", "top/layer.tar"},
+	}})
+	image := filepath.Join(t.TempDir(), "image.tar")
+	err := os.WriteFile(image, makeTar(t, []tarEntry{
+		{name: "base/layer.tar", data: layers[0]},
+		{name: "top/layer.tar", data: layers[1]},
+		{name: "config.json", data: []byte("{}")},
+		{name: "manifest.json", data: manifest},
+	}), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkImageRoots(t, image, want)
+}
+
+func TestLoadImageRoots_ociLayout(t *testing.T) {
+	layers, want := testImageLayers(t)
+	dir := t.TempDir()
+	writeBlob := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+		if err := os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "blobs", "sha256", digest), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return "sha256:" + digest
+	}
+	descs := []map[string]string{}
+	for _, layer := range layers {
+		descs = append(descs, map[string]string{"mediaType": "application/vnd.oci.image.layer.v1.tar", "digest": writeBlob(layer)})
+	}
+	manifest, _ := json.Marshal(map[string]any{"schemaVersion": 2, "layers": descs})
+	index, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"manifests": []map[string]any{
+			{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": writeBlob([]byte("{}")), "platform": map[string]string{"os": "unknown", "architecture": "unknown"}},
+			{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": writeBlob(manifest)},
+		},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "index.json"), index, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checkImageRoots(t, dir, want)
+}
@@ -0,0 +1,202 @@
+package ctl
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// DefaultKeyStorePassword is the default password of the JDK cacerts file.
+const DefaultKeyStorePassword = "changeit"
+
+const (
+	jksMagic   = 0xFEEDFEED
+	jceksMagic = 0xCECECECE
+
+	jksPrivateKeyTag  = 1
+	jksTrustedCertTag = 2
+	jksSecretKeyTag   = 3
+
+	// appended to the password for the keystore integrity digest
+	jksWhitener = "Mighty Aphrodite"
+)
+
+// LoadKeyStore loads the trusted certificate entries of a Java keystore file,
+// such as the JDK cacerts, see AppendCertsFromKeyStore.
+func LoadKeyStore(file, password string) (*CertStore, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s := NewCertStore()
+	if err := s.AppendCertsFromKeyStore(data, password); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return s, nil
+}
+
+// AppendCertsFromKeyStore appends the trusted certificate entries of a Java
+// keystore in JKS (or JCEKS) or PKCS #12 format. Certificates of private key
+// entries are not trust anchors and are skipped. The integrity of the keystore
+// is checked with password, unless it is empty.
+func (s *CertStore) AppendCertsFromKeyStore(data []byte, password string) error {
+	if len(data) >= 4 {
+		switch binary.BigEndian.Uint32(data) {
+		case jksMagic, jceksMagic:
+			return s.appendCertsFromJKS(data, password)
+		}
+	}
+	return s.appendCertsFromPKCS12(data, password)
+}
+
+// appendCertsFromPKCS12 appends the certificates of a PKCS #12 trust store as
+// written by keytool. Like Java, only certificates with the trustedKeyUsage
+// attribute are trust anchors, so keystores with other certificates or
+// private keys are rejected. A password-less keystore is read with any
+// password.
+func (s *CertStore) appendCertsFromPKCS12(data []byte, password string) error {
+	certs, err := pkcs12.DecodeTrustStore(data, password)
+	if err != nil && password != "" {
+		if nopass, e := pkcs12.DecodeTrustStore(data, ""); e == nil {
+			certs, err = nopass, nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		s.AddCert(cert)
+	}
+	return nil
+}
+
+// appendCertsFromJKS parses the keystore format of sun.security.provider.JavaKeyStore
+func (s *CertStore) appendCertsFromJKS(data []byte, password string) error {
+	if len(data) < sha1.Size {
+		return errors.New("jks: keystore too short")
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	if password != "" {
+		h := sha1.New()
+		for _, c := range utf16.Encode([]rune(password)) {
+			h.Write([]byte{byte(c >> 8), byte(c)})
+		}
+		h.Write([]byte(jksWhitener))
+		h.Write(body)
+		if subtle.ConstantTimeCompare(h.Sum(nil), digest) != 1 {
+			return errors.New("jks: keystore was tampered with, or password was incorrect")
+		}
+	}
+
+	r := &jksReader{r: bytes.NewReader(body)}
+	magic := r.uint32()
+	version := r.uint32()
+	if version != 1 && version != 2 {
+		return fmt.Errorf("jks: unsupported version %d", version)
+	}
+	count := r.uint32()
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		tag := r.uint32()
+		_ = r.utf()    // alias
+		_ = r.uint64() // creation date
+		switch tag {
+		case jksPrivateKeyTag:
+			r.bytes() // protected key
+			chain := r.uint32()
+			for j := uint32(0); j < chain && r.err == nil; j++ {
+				r.cert(version)
+			}
+		case jksTrustedCertTag:
+			der := r.cert(version)
+			if r.err != nil {
+				break
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return fmt.Errorf("jks: %w", err)
+			}
+			s.AddCert(cert)
+		case jksSecretKeyTag:
+			if magic == jceksMagic {
+				return errors.New("jks: JCEKS secret key entries are not supported")
+			}
+			fallthrough
+		default:
+			return fmt.Errorf("jks: unknown entry tag %d", tag)
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("jks: %w", r.err)
+	}
+	return nil
+}
+
+// jksReader reads the big-endian DataInputStream encoding, keeping the first error
+type jksReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *jksReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		r.err = err
+		return nil
+	}
+	return b
+}
+
+func (r *jksReader) uint32() uint32 {
+	if b := r.read(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *jksReader) uint64() uint64 {
+	if b := r.read(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// utf reads a modified UTF-8 string as written by DataOutputStream.writeUTF
+func (r *jksReader) utf() string {
+	b := r.read(2)
+	if b == nil {
+		return ""
+	}
+	return string(r.read(int(binary.BigEndian.Uint16(b))))
+}
+
+func (r *jksReader) bytes() []byte {
+	n := r.uint32()
+	if n > 1<<24 {
+		r.err = fmt.Errorf("entry of %d bytes is too large", n)
+		return nil
+	}
+	return r.read(int(n))
+}
+
+// cert reads a certificate, prefixed by its type since version 2
+func (r *jksReader) cert(version uint32) []byte {
+	if version == 2 {
+		if typ := r.utf(); r.err == nil && typ != "X.509" {
+			r.err = fmt.Errorf("unsupported certificate type %q", typ)
+			return nil
+		}
+	}
+	return r.bytes()
+}
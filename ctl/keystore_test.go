@@ -0,0 +1,140 @@
+package ctl
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// makeJKS writes a version 2 JKS keystore with a private key entry for
+// keyCert and a trusted certificate entry for each of trusted.
+func makeJKS(password string, keyCert *Cert, trusted ...*Cert) []byte {
+	var buf bytes.Buffer
+	w := func(v any) { _ = binary.Write(&buf, binary.BigEndian, v) }
+	utf := func(s string) {
+		w(uint16(len(s)))
+		buf.WriteString(s)
+	}
+	cert := func(c *Cert) {
+		utf("X.509")
+		w(uint32(len(c.Raw)))
+		buf.Write(c.Raw)
+	}
+
+	w(uint32(jksMagic))
+	w(uint32(2))
+	w(uint32(1 + len(trusted)))
+	w(uint32(jksPrivateKeyTag))
+	utf("server")
+	w(time.Now().UnixMilli())
+	w(uint32(4))
+	buf.WriteString("key!")
+	w(uint32(1))
+	cert(keyCert)
+	for i, c := range trusted {
+		w(uint32(jksTrustedCertTag))
+		utf(string(rune('a' + i)))
+		w(time.Now().UnixMilli())
+		cert(c)
+	}
+
+	h := sha1.New()
+	for _, c := range utf16.Encode([]rune(password)) {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write([]byte(jksWhitener))
+	h.Write(buf.Bytes())
+	return append(buf.Bytes(), h.Sum(nil)...)
+}
+
+func TestCertStore_AppendCertsFromKeyStore_JKS(t *testing.T) {
+	key := newValidCert(t, "Server")
+	c1 := newValidCert(t, "Root 1")
+	c2 := newValidCert(t, "Root 2")
+	jks := makeJKS(DefaultKeyStorePassword, key, c1, c2)
+
+	tests := []struct {
+		name      string
+		password  string
+		wantCount int
+		wantErr   bool
+	}{
+		{"default password", DefaultKeyStorePassword, 2, false},
+		{"no integrity check", "", 2, false},
+		{"wrong password", "secret", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewCertStore()
+			err := s.AppendCertsFromKeyStore(jks, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AppendCertsFromKeyStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(s.Certs) != tt.wantCount {
+				t.Errorf("AppendCertsFromKeyStore() loaded %d certs, want %d", len(s.Certs), tt.wantCount)
+			}
+			if s.contains(key.Certificate) {
+				t.Errorf("AppendCertsFromKeyStore() loaded the certificate of a private key entry")
+			}
+		})
+	}
+
+	s := NewCertStore()
+	if err := s.AppendCertsFromKeyStore(jks[:len(jks)-30], ""); err == nil {
+		t.Errorf("AppendCertsFromKeyStore() error = nil for a truncated keystore")
+	}
+}
+
+func TestLoadKeyStore_PKCS12(t *testing.T) {
+	roots := []*x509.Certificate{
+		newValidCert(t, "Keystore Test Root 2").Certificate,
+		newValidCert(t, "Keystore Test Root 3").Certificate,
+	}
+	store := func(enc *pkcs12.Encoder, password string) string {
+		data, err := enc.EncodeTrustStore(roots, password)
+		if err != nil {
+			t.Fatalf("EncodeTrustStore() error = %v", err)
+		}
+		file := filepath.Join(t.TempDir(), "truststore.p12")
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		password string
+		wantErr  bool
+	}{
+		{"pbes2 aes-256, sha256 mac", store(pkcs12.Modern, DefaultKeyStorePassword), DefaultKeyStorePassword, false},
+		{"3des, sha1 mac", store(pkcs12.LegacyDES, DefaultKeyStorePassword), DefaultKeyStorePassword, false},
+		{"password-less", store(pkcs12.Passwordless, ""), DefaultKeyStorePassword, false},
+		{"wrong password", store(pkcs12.Modern, DefaultKeyStorePassword), "secret", true},
+		{"with key entry", "testdata/truststore.p12", DefaultKeyStorePassword, true},
+		{"without trustedKeyUsage", "testdata/truststore-legacy.p12", DefaultKeyStorePassword, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := LoadKeyStore(tt.file, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadKeyStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(s.Certs) != 2 {
+				t.Fatalf("LoadKeyStore() loaded %d certs, want 2", len(s.Certs))
+			}
+		})
+	}
+}
@@ -6,12 +6,16 @@ import (
 	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/carlmjohnson/requests"
@@ -42,16 +46,73 @@ func csvReadToMap(in io.Reader) ([]map[string]string, error) {
 	return ret, nil
 }
 
-func getBody(url string) ([]byte, error) {
+// getBody fetches url, which may also be a file:// URL, e.g. of a local mirror.
+// Remote payloads are cached if cl was created by NewHTTPClient with a
+// CacheDir, see cacheTransport.
+func getBody(ctx context.Context, cl *http.Client, url string) ([]byte, error) {
+	if strings.HasPrefix(url, "file:") {
+		return readFileURL(url)
+	}
 	var buf bytes.Buffer
-	err := requests.URL(url).
-		ToBytesBuffer(&buf).Fetch(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("get remote csv fail: %w", err)
+	if err := requests.URL(url).Client(cl).ToBytesBuffer(&buf).Fetch(ctx); err != nil {
+		return nil, fmt.Errorf("get remote data fail: %w", err)
 	}
 	return buf.Bytes(), nil
 }
 
+// maxConcurrentFetches bounds the number of downloads a CTL runs at a time
+const maxConcurrentFetches = 4
+
+// fetchConcurrently runs fetches, at most maxConcurrentFetches at a time,
+// and returns the errors of all that failed
+func fetchConcurrently(fetches ...func() error) error {
+	errs := make([]error, len(fetches))
+	sem := make(chan struct{}, maxConcurrentFetches)
+	var wg sync.WaitGroup
+	for i, fetch := range fetches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, fetch func() error) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = fetch()
+		}(i, fetch)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// readFileURL reads the local file of a file:// URL, either absolute
+// (file:///path/to/file) or relative to the working directory (file:path).
+func readFileURL(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("%s: file URLs of remote hosts are not supported", rawURL)
+	}
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	} else if len(path) > 2 && path[0] == '/' && path[2] == ':' { // file:///C:/path on Windows
+		path = path[1:]
+	}
+	return os.ReadFile(filepath.FromSlash(path))
+}
+
+// resolveURL resolves ref, e.g. a link in a fetched page, against base
+func resolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
+
 func getChecksum(data []byte) string {
 	hash := sha256.Sum256(data)
 	return strings.ToUpper(hex.EncodeToString(hash[:]))
@@ -94,5 +155,19 @@ func parseDate(date string) (t time.Time, err error) {
 	if err != nil {
 		t, err = time.Parse("January _2, 2006", date)
 	}
+	if err != nil {
+		t, err = time.Parse("2006.01.02", date)
+	}
 	return t, err
 }
+
+// splitList splits a CCADB list column, separated by semicolons or commas
+func splitList(s string) []string {
+	ret := []string{}
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
@@ -1,7 +1,13 @@
 package ctl
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -78,3 +84,64 @@ func Test_parseDate(t *testing.T) {
 		})
 	}
 }
+
+func Test_readFileURL(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(file, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"file://" + filepath.ToSlash(file), "file://localhost" + filepath.ToSlash(file)} {
+		if got, err := getBody(context.Background(), nil, u); err != nil || string(got) != "data" {
+			t.Errorf("getBody(%q) = %q, %v, want %q", u, got, err, "data")
+		}
+	}
+	if _, err := getBody(context.Background(), nil, "file://mirror.example.com/report.csv"); err == nil {
+		t.Errorf("getBody() of a remote file URL, want error")
+	}
+}
+
+func Test_resolveURL(t *testing.T) {
+	tests := []struct {
+		base, ref, want string
+	}{
+		{"https://support.apple.com/en-us/HT209143", "https://support.apple.com/en-us/HT213464", "https://support.apple.com/en-us/HT213464"},
+		{"https://support.apple.com/en-us/HT209143", "/en-us/HT213464", "https://support.apple.com/en-us/HT213464"},
+		{"file:///srv/mirror/apple/index.html", "HT213464.html", "file:///srv/mirror/apple/HT213464.html"},
+	}
+	for _, tt := range tests {
+		if got, err := resolveURL(tt.base, tt.ref); err != nil || got != tt.want {
+			t.Errorf("resolveURL(%q, %q) = %q, %v, want %q", tt.base, tt.ref, got, err, tt.want)
+		}
+	}
+}
+
+func Test_fetchConcurrently(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	fetches := []func() error{}
+	for i := 0; i < 3*maxConcurrentFetches; i++ {
+		i := i
+		fetches = append(fetches, func() error {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if i%5 == 0 {
+				return fmt.Errorf("fetch %d failed", i)
+			}
+			return nil
+		})
+	}
+	err := fetchConcurrently(fetches...)
+	if err == nil || !strings.Contains(err.Error(), "fetch 0 failed") || !strings.Contains(err.Error(), "fetch 10 failed") {
+		t.Errorf("fetchConcurrently() error = %v, want the errors of fetch 0, 5 and 10", err)
+	}
+	if peak > maxConcurrentFetches {
+		t.Errorf("fetchConcurrently() ran %d fetches at a time, want at most %d", peak, maxConcurrentFetches)
+	}
+}
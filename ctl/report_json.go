@@ -0,0 +1,162 @@
+package ctl
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONReportVersion is the schema version of the document written by
+// WriteJSONReport. It is increased on every incompatible change.
+const JSONReportVersion = 2
+
+// JSONDocument is the machine-readable form of one or more VerifyResults.
+type JSONDocument struct {
+	Version     int          `json:"version"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Results     []JSONResult `json:"results"`
+}
+
+// JSONResult is the machine-readable form of a VerifyResult.
+type JSONResult struct {
+	Vendor string     `json:"vendor"`
+	Totals JSONTotals `json:"totals"`
+	// ExpiryWindowDays is the ExpiryWindow of the expiring certificates
+	ExpiryWindowDays int        `json:"expiry_window_days"`
+	Trusted          []JSONCert `json:"trusted"`
+	Partial          []JSONCert `json:"partial"`
+	Allowed          []JSONCert `json:"allowed"`
+	Removed          []JSONCert `json:"removed"`
+	Unknown          []JSONCert `json:"unknown"`
+	// Missing roots of the vendor have no certificate, only their entry
+	Missing []JSONEntry `json:"missing"`
+}
+
+type JSONTotals struct {
+	Total   int `json:"total"`
+	Trusted int `json:"trusted"`
+	Partial int `json:"partial"`
+	Allowed int `json:"allowed"`
+	Removed int `json:"removed"`
+	Unknown int `json:"unknown"`
+	Missing int `json:"missing"`
+	// Expired, Expiring and NotYetValid certificates of all buckets
+	Expired     int `json:"expired"`
+	Expiring    int `json:"expiring"`
+	NotYetValid int `json:"not_yet_valid"`
+}
+
+type JSONCert struct {
+	SHA256    string    `json:"sha256"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	// Name of the entry in the vendor's CTL (or in the allow list)
+	Name string `json:"name,omitempty"`
+	// Usages and Constraint of the entry explain partially distrusted certificates
+	Usages     []string    `json:"usages,omitempty"`
+	Constraint *Constraint `json:"constraint,omitempty"`
+	// Status, Reason, RemovedAt and URL explain why a certificate was removed
+	Status    string     `json:"status,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	RemovedAt *time.Time `json:"removed_at,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	// Owner of the root in an internal CTL
+	Owner string `json:"owner,omitempty"`
+	// Expiry is "expired", "expiring" within the ExpiryWindow or "not_yet_valid"
+	Expiry string `json:"expiry,omitempty"`
+}
+
+// JSONEntry is a root trusted by the vendor that was not found.
+type JSONEntry struct {
+	SHA256 string   `json:"sha256"`
+	Name   string   `json:"name,omitempty"`
+	Usages []string `json:"usages,omitempty"`
+	Owner  string   `json:"owner,omitempty"`
+}
+
+// JSONReport converts the result to its machine-readable form.
+func (result *VerifyResult) JSONReport() JSONResult {
+	now := time.Now()
+	return JSONResult{
+		Vendor: result.Vendor,
+		Totals: JSONTotals{
+			Total:       result.Total,
+			Trusted:     len(result.TrustedCerts),
+			Partial:     len(result.PartialCerts),
+			Allowed:     len(result.AllowedCerts),
+			Removed:     len(result.RemovedCerts),
+			Unknown:     len(result.UnknownCerts),
+			Missing:     len(result.MissingEntries),
+			Expired:     len(result.Expired(now)),
+			Expiring:    len(result.Expiring(now, result.ExpiryWindow)),
+			NotYetValid: len(result.NotYetValid(now)),
+		},
+		ExpiryWindowDays: int(result.ExpiryWindow / (24 * time.Hour)),
+		Trusted:          result.jsonCerts(result.TrustedCerts),
+		Partial:          result.jsonCerts(result.PartialCerts),
+		Allowed:          result.jsonCerts(result.AllowedCerts),
+		Removed:          result.jsonCerts(result.RemovedCerts),
+		Unknown:          result.jsonCerts(result.UnknownCerts),
+		Missing:          result.jsonMissing(),
+	}
+}
+
+func (result *VerifyResult) jsonMissing() []JSONEntry {
+	ret := make([]JSONEntry, 0, len(result.MissingEntries))
+	for _, k := range result.Missing() {
+		entry := result.MissingEntries[k]
+		ret = append(ret, JSONEntry{
+			SHA256: k,
+			Name:   entry.Name,
+			Usages: entry.Usages,
+			Owner:  entry.Owner,
+		})
+	}
+	return ret
+}
+
+func (result *VerifyResult) jsonCerts(certs []*Cert) []JSONCert {
+	now := time.Now()
+	ret := make([]JSONCert, 0, len(certs))
+	for _, cert := range certs {
+		entry := result.entries[cert.Checksum]
+		var removedAt *time.Time
+		if !entry.RemovedAt.IsZero() {
+			removedAt = &entry.RemovedAt
+		}
+		ret = append(ret, JSONCert{
+			SHA256:     cert.Checksum,
+			Subject:    cert.Subject.String(),
+			Issuer:     cert.Issuer.String(),
+			NotBefore:  cert.NotBefore.UTC(),
+			NotAfter:   cert.NotAfter.UTC(),
+			Name:       entry.Name,
+			Usages:     entry.Usages,
+			Constraint: entry.Constraint,
+			Status:     entry.Status,
+			Reason:     entry.Reason,
+			RemovedAt:  removedAt,
+			URL:        entry.URL,
+			Owner:      entry.Owner,
+			Expiry:     result.expiry(cert, now),
+		})
+	}
+	return ret
+}
+
+// WriteJSONReport writes the results to w as an indented JSON document.
+func WriteJSONReport(w io.Writer, results ...*VerifyResult) error {
+	report := JSONDocument{
+		Version:     JSONReportVersion,
+		GeneratedAt: time.Now().UTC(),
+		Results:     make([]JSONResult, 0, len(results)),
+	}
+	for _, result := range results {
+		report.Results = append(report.Results, result.JSONReport())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
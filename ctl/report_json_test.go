@@ -0,0 +1,93 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJSONReport(t *testing.T) {
+	trusted := newValidCert(t, "Trusted Root")
+	removed := newValidCert(t, "Removed Root")
+
+	ctl := &MozillaCTL{CTL: newTestCTL([]*Cert{trusted}, nil)}
+	ctl.Removed[removed.Checksum] = Entry{Name: "Removed Root (Mozilla)", Reason: "Bug 1234567", URL: MozillaBugURL + "1234567"}
+	ctl.Trusted["AA"] = Entry{Name: "New Root (Mozilla)", Usages: []string{TrustWebsites}}
+	ctl.Trusted["BB"] = Entry{Name: "Email Root (Mozilla)", Usages: []string{TrustEmail}}
+	ret := ctl.Verify([]*Cert{trusted, removed}, Entrys{})
+
+	var buf bytes.Buffer
+	if err := WriteJSONReport(&buf, ret); err != nil {
+		t.Fatalf("WriteJSONReport() error = %v", err)
+	}
+	var doc JSONDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteJSONReport() wrote invalid JSON: %v", err)
+	}
+	if doc.Version != JSONReportVersion {
+		t.Errorf("Version = %d, want %d", doc.Version, JSONReportVersion)
+	}
+	if len(doc.Results) != 1 {
+		t.Fatalf("len(Results) = %d, want 1", len(doc.Results))
+	}
+	got := doc.Results[0]
+	if got.Vendor != "Mozilla" || got.Totals.Total != 2 || got.Totals.Trusted != 1 || got.Totals.Removed != 1 {
+		t.Errorf("Results[0] = %+v, unexpected vendor or totals", got)
+	}
+	if len(got.Removed) != 1 || got.Removed[0].SHA256 != removed.Checksum || got.Removed[0].Name != "Removed Root (Mozilla)" {
+		t.Errorf("Results[0].Removed = %+v, want %s", got.Removed, removed.Checksum)
+	}
+	if len(got.Removed) == 1 && (got.Removed[0].Reason != "Bug 1234567" || got.Removed[0].URL != MozillaBugURL+"1234567" || got.Removed[0].RemovedAt != nil) {
+		t.Errorf("Results[0].Removed[0] = %+v, want the reason and link of the entry", got.Removed[0])
+	}
+	if got.Totals.Missing != 1 || len(got.Missing) != 1 || got.Missing[0].SHA256 != "AA" || got.Missing[0].Name != "New Root (Mozilla)" {
+		t.Errorf("Results[0].Missing = %+v, want only the root trusted for websites", got.Missing)
+	}
+	// both roots expire in an hour
+	if got.ExpiryWindowDays != 90 || got.Totals.Expired != 0 || got.Totals.Expiring != 2 || got.Totals.NotYetValid != 0 {
+		t.Errorf("Results[0] expiry window = %d, totals = %+v, want both roots expiring within 90 days", got.ExpiryWindowDays, got.Totals)
+	}
+	for _, c := range append(got.Trusted, got.Removed...) {
+		if c.Expiry != expiryExpiring {
+			t.Errorf("Results[0] expiry of %s = %q, want %q", c.Name, c.Expiry, expiryExpiring)
+		}
+	}
+	ret.ExpiryWindow = 0
+	buf.Reset()
+	if err := WriteJSONReport(&buf, ret); err != nil {
+		t.Fatalf("WriteJSONReport() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"expiry_window_days": 0`) || strings.Contains(buf.String(), `"expiry":`) {
+		t.Errorf("WriteJSONReport() without an expiry window = %s, want no expiring roots", buf.String())
+	}
+	if got.Unknown == nil {
+		t.Errorf("Results[0].Unknown is null, want empty list")
+	}
+}
+
+func TestWriteJSONReport_constraint(t *testing.T) {
+	ev := newValidCert(t, "EV Root")
+	partial := newValidCert(t, "Partial Root")
+
+	ctl := &MozillaCTL{CTL: newTestCTL(nil, nil)}
+	ctl.Trusted[ev.Checksum] = Entry{Name: "EV Root", Constraint: &Constraint{EVPolicyOIDs: []string{"2.23.140.1.1"}}}
+	ctl.Trusted[partial.Checksum] = Entry{Name: "Partial Root", Constraint: &Constraint{EVPolicyOIDs: []string{}, DistrustTLSAfter: time.Date(2022, 11, 30, 0, 0, 0, 0, time.UTC)}}
+
+	var buf bytes.Buffer
+	if err := WriteJSONReport(&buf, ctl.Verify([]*Cert{ev, partial}, Entrys{})); err != nil {
+		t.Fatalf("WriteJSONReport() error = %v", err)
+	}
+	out := buf.String()
+	if strings.Count(out, `"distrust_tls_after": "2022-11-30T00:00:00Z"`) != 1 || strings.Contains(out, `"distrust_email_after"`) || strings.Contains(out, "0001-01-01") {
+		t.Errorf("WriteJSONReport() = %s, want only the set distrust date", out)
+	}
+	var doc JSONDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("WriteJSONReport() wrote invalid JSON: %v", err)
+	}
+	if c := doc.Results[0].Trusted[0].Constraint; c == nil || len(c.EVPolicyOIDs) != 1 || !c.DistrustTLSAfter.IsZero() {
+		t.Errorf("Results[0].Trusted[0].Constraint = %+v, want the EV policy only", c)
+	}
+}
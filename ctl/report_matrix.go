@@ -0,0 +1,134 @@
+package ctl
+
+import (
+	"sort"
+
+	"github.com/pterm/pterm"
+)
+
+// Status of a certificate in a VerifyResult
+const (
+	StatusTrusted = "Trusted"
+	StatusPartial = "Partial"
+	StatusAllowed = "Allowed"
+	StatusRemoved = "Removed"
+	StatusUnknown = "Unknown"
+)
+
+// Status returns the bucket the certificate with the checksum is classified
+// into, or an empty string if it was not verified.
+func (result *VerifyResult) Status(checksum string) string {
+	for status, certs := range map[string][]*Cert{
+		StatusTrusted: result.TrustedCerts,
+		StatusPartial: result.PartialCerts,
+		StatusAllowed: result.AllowedCerts,
+		StatusRemoved: result.RemovedCerts,
+		StatusUnknown: result.UnknownCerts,
+	} {
+		for _, cert := range certs {
+			if cert.Checksum == checksum {
+				return status
+			}
+		}
+	}
+	return ""
+}
+
+// MatrixRow is the status of one certificate across vendors
+type MatrixRow struct {
+	Cert *Cert
+	// Statuses in the order of the results passed to Matrix
+	Statuses []string
+}
+
+// Consistent reports whether all vendors classify the certificate the same way.
+func (row MatrixRow) Consistent() bool {
+	for _, s := range row.Statuses {
+		if s != row.Statuses[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// Matrix returns one row per certificate verified by any of the results,
+// certificates on which the vendors disagree first.
+func Matrix(results ...*VerifyResult) []MatrixRow {
+	certs := []*Cert{}
+	seen := map[string]bool{}
+	for _, result := range results {
+		for _, bucket := range [][]*Cert{result.TrustedCerts, result.PartialCerts, result.AllowedCerts, result.RemovedCerts, result.UnknownCerts} {
+			for _, cert := range bucket {
+				if !seen[cert.Checksum] {
+					seen[cert.Checksum] = true
+					certs = append(certs, cert)
+				}
+			}
+		}
+	}
+
+	rows := make([]MatrixRow, 0, len(certs))
+	for _, cert := range certs {
+		row := MatrixRow{Cert: cert}
+		for _, result := range results {
+			row.Statuses = append(row.Statuses, result.Status(cert.Checksum))
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		ci, cj := rows[i].Consistent(), rows[j].Consistent()
+		if ci != cj {
+			return !ci
+		}
+		return pkixName(rows[i].Cert.Subject) < pkixName(rows[j].Cert.Subject)
+	})
+	return rows
+}
+
+// MatrixConsoleReport renders the trust matrix of the results, one row per
+// certificate and one column per vendor.
+func MatrixConsoleReport(results ...*VerifyResult) (output string) {
+	rows := Matrix(results...)
+	header := []string{"Certificate", "SHA256"}
+	for _, result := range results {
+		header = append(header, result.Vendor)
+	}
+	data := pterm.TableData{header}
+	inconsistent := 0
+	for _, row := range rows {
+		if !row.Consistent() {
+			inconsistent++
+		}
+		line := []string{pkixName(row.Cert.Subject), row.Cert.Checksum[:16]}
+		for _, status := range row.Statuses {
+			line = append(line, colorStatus(status))
+		}
+		data = append(data, line)
+	}
+
+	output += pterm.ThemeDefault.InfoMessageStyle.Sprintf("%d of %d certificates are classified differently by the vendors.\n", inconsistent, len(rows))
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(data).Srender()
+	if err != nil {
+		output += pterm.Error.Sprintf("%v", err)
+		return
+	}
+	output += table + "\n"
+	return
+}
+
+func colorStatus(status string) string {
+	switch status {
+	case StatusTrusted:
+		return pterm.Green(status)
+	case StatusPartial:
+		return pterm.Magenta(status)
+	case StatusAllowed:
+		return pterm.Cyan(status)
+	case StatusRemoved:
+		return pterm.Red(status)
+	case StatusUnknown:
+		return pterm.Yellow(status)
+	default:
+		return status
+	}
+}
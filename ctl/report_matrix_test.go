@@ -0,0 +1,29 @@
+package ctl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	both := newValidCert(t, "Trusted By Both")
+	split := newValidCert(t, "Removed By Mozilla")
+	certs := []*Cert{both, split}
+
+	mozilla := &MozillaCTL{CTL: newTestCTL([]*Cert{both}, []*Cert{split})}
+	microsoft := &MicrosoftCTL{CTL: newTestCTL(certs, nil)}
+
+	rows := Matrix(mozilla.Verify(certs, Entrys{}), microsoft.Verify(certs, Entrys{}))
+	if len(rows) != 2 {
+		t.Fatalf("len(Matrix()) = %d, want 2", len(rows))
+	}
+	if rows[0].Cert != split || rows[0].Consistent() {
+		t.Errorf("Matrix()[0] = %s, want the inconsistent %s first", rows[0].Cert.Subject, split.Subject)
+	}
+	if want := []string{StatusRemoved, StatusTrusted}; !reflect.DeepEqual(rows[0].Statuses, want) {
+		t.Errorf("Matrix()[0].Statuses = %v, want %v", rows[0].Statuses, want)
+	}
+	if want := []string{StatusTrusted, StatusTrusted}; !reflect.DeepEqual(rows[1].Statuses, want) {
+		t.Errorf("Matrix()[1].Statuses = %v, want %v", rows[1].Statuses, want)
+	}
+}
@@ -0,0 +1,257 @@
+package ctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolURI = "https://github.com/canstand/ctlcheck"
+)
+
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+	// artifacts are the paths of the checked certificates, see WriteSARIFReport
+	artifacts []string
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	Help                 sarifMessage       `json:"help"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIFReport writes the partially distrusted, removed and unknown certificates and the
+// missing roots of the results to w as a SARIF 2.1.0 log, one result per certificate.
+// artifacts are the paths of the checked CA bundles, directories, image or keystore, the
+// physical locations of all results. Without artifacts, e.g. for the system root CAs that
+// may be read from an OS API, the results only have the logical location of the certificate.
+func WriteSARIFReport(w io.Writer, artifacts []string, results ...*VerifyResult) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           "ctlcheck",
+				InformationURI: sarifToolURI,
+				Rules:          []sarifRule{},
+			},
+		},
+		Results:   []sarifResult{},
+		artifacts: artifacts,
+	}
+	for _, result := range results {
+		vendor, name := sarifID(result.Vendor), sarifName(result.Vendor)
+		run.addResults(result, sarifRule{
+			ID:                   "ctl/partially-distrusted-by-" + vendor,
+			Name:                 "PartiallyDistrustedBy" + name,
+			ShortDescription:     sarifMessage{Text: fmt.Sprintf("Root certificate is partially distrusted by %s", result.Vendor)},
+			Help:                 sarifMessage{Text: result.partialDesc},
+			DefaultConfiguration: sarifConfiguration{Level: "note"},
+		}, result.PartialCerts)
+		run.addResults(result, sarifRule{
+			ID:                   "ctl/removed-by-" + vendor,
+			Name:                 "RemovedBy" + name,
+			ShortDescription:     sarifMessage{Text: fmt.Sprintf("Root certificate has been removed by %s", result.Vendor)},
+			Help:                 sarifMessage{Text: result.removedDesc},
+			DefaultConfiguration: sarifConfiguration{Level: "error"},
+		}, result.RemovedCerts)
+		run.addResults(result, sarifRule{
+			ID:                   "ctl/unknown-to-" + vendor,
+			Name:                 "UnknownTo" + name,
+			ShortDescription:     sarifMessage{Text: fmt.Sprintf("Root certificate is unknown to %s", result.Vendor)},
+			Help:                 sarifMessage{Text: result.unknownHelp()},
+			DefaultConfiguration: sarifConfiguration{Level: "warning"},
+		}, result.UnknownCerts)
+		run.addMissing(result, sarifRule{
+			ID:                   "ctl/missing-from-" + vendor,
+			Name:                 "MissingFrom" + name,
+			ShortDescription:     sarifMessage{Text: fmt.Sprintf("Root certificate trusted by %s is missing", result.Vendor)},
+			Help:                 sarifMessage{Text: result.missingDesc},
+			DefaultConfiguration: sarifConfiguration{Level: "note"},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
+
+// addResults registers rule and adds a result for each of certs
+func (run *sarifRun) addResults(result *VerifyResult, rule sarifRule, certs []*Cert) {
+	if len(certs) == 0 {
+		return
+	}
+	ruleIndex := len(run.Tool.Driver.Rules)
+	run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	for _, cert := range certs {
+		name := cert.Subject.String()
+		if entry := result.entries[cert.Checksum]; entry.Name != "" {
+			name = entry.Name
+		}
+		text := fmt.Sprintf("%s: %s (SHA256 %s)", rule.ShortDescription.Text, name, cert.Checksum)
+		for _, d := range result.certDetails(cert) {
+			text += fmt.Sprintf(", %s: %s", strings.ToLower(d.Label), d.Value)
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    rule.ID,
+			RuleIndex: ruleIndex,
+			Level:     rule.DefaultConfiguration.Level,
+			Message: sarifMessage{
+				Text: text,
+			},
+			Locations: run.locations(name, cert.Checksum),
+			PartialFingerprints: map[string]string{
+				"sha256": cert.Checksum,
+			},
+		})
+	}
+}
+
+// addMissing registers rule and adds a result for each missing root of result
+func (run *sarifRun) addMissing(result *VerifyResult, rule sarifRule) {
+	if len(result.MissingEntries) == 0 {
+		return
+	}
+	ruleIndex := len(run.Tool.Driver.Rules)
+	run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	for _, checksum := range result.Missing() {
+		name := result.MissingEntries[checksum].Name
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    rule.ID,
+			RuleIndex: ruleIndex,
+			Level:     rule.DefaultConfiguration.Level,
+			Message: sarifMessage{
+				Text: fmt.Sprintf("%s: %s (SHA256 %s)", rule.ShortDescription.Text, name, checksum),
+			},
+			Locations: run.locations(name, checksum),
+			PartialFingerprints: map[string]string{
+				"sha256": checksum,
+			},
+		})
+	}
+}
+
+// locations returns the locations of the certificate with name and checksum,
+// one per artifact, or a logical location only if there are no artifacts
+func (run *sarifRun) locations(name, checksum string) []sarifLocation {
+	logical := []sarifLogicalLocation{{
+		Name:               name,
+		FullyQualifiedName: "sha256:" + checksum,
+		Kind:               "object",
+	}}
+	if len(run.artifacts) == 0 {
+		return []sarifLocation{{LogicalLocations: logical}}
+	}
+	ret := make([]sarifLocation, 0, len(run.artifacts))
+	for _, artifact := range run.artifacts {
+		ret = append(ret, sarifLocation{
+			PhysicalLocation: &sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(artifact)},
+			},
+			LogicalLocations: logical,
+		})
+	}
+	return ret
+}
+
+// sarifID returns the vendor in lower case, with every run of other
+// characters than letters and digits replaced by a dash, e.g. "corp-pki"
+func sarifID(vendor string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(vendor) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// sarifName returns the vendor without other characters than letters and
+// digits, e.g. "CorpPKI"
+func sarifName(vendor string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, vendor)
+}
+
+func (result *VerifyResult) unknownHelp() string {
+	if result.unknownDesc != "" {
+		return result.unknownDesc
+	}
+	return fmt.Sprintf("The certificate is neither included in nor removed from the %s CTL, and is not allowed in the config file.\n", result.Vendor)
+}
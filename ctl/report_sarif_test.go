@@ -0,0 +1,87 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIFReport(t *testing.T) {
+	trusted := newValidCert(t, "Trusted Root")
+	removed := newValidCert(t, "Removed Root")
+	unknown := newValidCert(t, "Unknown Root")
+
+	ctl := &MozillaCTL{CTL: newTestCTL([]*Cert{trusted}, []*Cert{removed})}
+	ctl.Trusted["AA"] = Entry{Name: "New Root", Usages: []string{TrustWebsites}}
+	ret := ctl.Verify([]*Cert{trusted, removed, unknown}, Entrys{})
+	// an internal CTL with a free-form label
+	corp := newTestCTL([]*Cert{trusted}, []*Cert{unknown}).VerifyAs("Corp PKI", []*Cert{trusted, unknown}, Entrys{})
+
+	var buf bytes.Buffer
+	if err := WriteSARIFReport(&buf, []string{"certs/ca-bundle.pem"}, ret, corp); err != nil {
+		t.Fatalf("WriteSARIFReport() error = %v", err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("WriteSARIFReport() wrote invalid JSON: %v", err)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("WriteSARIFReport() version = %q, runs = %d", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Results) != 4 {
+		t.Fatalf("len(Results) = %d, want 4", len(run.Results))
+	}
+	want := map[string]string{
+		"ctl/removed-by-mozilla":   "error",
+		"ctl/unknown-to-mozilla":   "warning",
+		"ctl/missing-from-mozilla": "note",
+		"ctl/removed-by-corp-pki":  "error",
+	}
+	for _, r := range run.Results {
+		if want[r.RuleID] != r.Level {
+			t.Errorf("result %s has level %q, want %q", r.RuleID, r.Level, want[r.RuleID])
+		}
+		if run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID {
+			t.Errorf("result %s points to rule %d (%s)", r.RuleID, r.RuleIndex, run.Tool.Driver.Rules[r.RuleIndex].ID)
+		}
+		if len(r.Locations) != 1 || r.Locations[0].PhysicalLocation == nil || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "certs/ca-bundle.pem" {
+			t.Errorf("result %s locations = %+v, want the checked bundle", r.RuleID, r.Locations)
+		}
+	}
+	if r := run.Results[2]; r.RuleID != "ctl/missing-from-mozilla" || r.Message.Text == "" || r.Locations[0].LogicalLocations[0].Name != "New Root" {
+		t.Errorf("Results[2] = %+v, want New Root missing from Mozilla", r)
+	}
+	if name := run.Tool.Driver.Rules[run.Results[3].RuleIndex].Name; name != "RemovedByCorpPKI" {
+		t.Errorf("rule name = %q, want RemovedByCorpPKI", name)
+	}
+	for _, rule := range run.Tool.Driver.Rules {
+		if rule.Help.Text == "" {
+			t.Errorf("rule %s has no help", rule.ID)
+		}
+	}
+
+	// the system root CAs have no path
+	buf.Reset()
+	if err := WriteSARIFReport(&buf, nil, corp); err != nil {
+		t.Fatalf("WriteSARIFReport() error = %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("physicalLocation")) {
+		t.Errorf("WriteSARIFReport() without artifacts wrote a physical location: %s", buf.Bytes())
+	}
+	log = sarifLog{}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("WriteSARIFReport() wrote invalid JSON: %v", err)
+	}
+	if locs := log.Runs[0].Results[0].Locations; len(locs) != 1 || locs[0].LogicalLocations[0].FullyQualifiedName != "sha256:"+unknown.Checksum {
+		t.Errorf("WriteSARIFReport() without artifacts locations = %+v, want the logical location of the certificate", locs)
+	}
+}
+
+func Test_sarifID(t *testing.T) {
+	for vendor, want := range map[string]string{"Mozilla": "mozilla", "Corp PKI": "corp-pki", " ACME (Test) CA ": "acme-test-ca"} {
+		if got := sarifID(vendor); got != want {
+			t.Errorf("sarifID(%q) = %q, want %q", vendor, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package ctl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// CTLSource is the certificate trust list of a vendor, which certificates
+// are checked against. MozillaCTL, AppleCTL, MicrosoftCTL, ChromeCTL,
+// OpenJDKCTL and InternalCTL are the built-in ones, more can be added with
+// Register.
+//
+// Sources are saved to and loaded from ctlcheck.yml with gopkg.in/yaml.v3,
+// so their state should be exported fields with yaml tags.
+type CTLSource interface {
+	// Name is the name the source is registered and selected with -vendor
+	// by, e.g. "mozilla"
+	Name() string
+	// Vendor is the name shown in reports, e.g. "Mozilla"
+	Vendor() string
+	// Fetch updates the CTL from the vendor, using cl for HTTP requests
+	Fetch(ctx context.Context, cl *http.Client) error
+	// Verify sorts certs by their status in the CTL, see (*CTL).VerifyAs
+	Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult
+	// TrustList returns the fetched CTL, nil or empty if not fetched yet
+	TrustList() *CTL
+}
+
+var (
+	_ CTLSource = (*MozillaCTL)(nil)
+	_ CTLSource = (*AppleCTL)(nil)
+	_ CTLSource = (*MicrosoftCTL)(nil)
+	_ CTLSource = (*ChromeCTL)(nil)
+	_ CTLSource = (*OpenJDKCTL)(nil)
+	_ CTLSource = (*InternalCTL)(nil)
+)
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = []sourceFactory{}
+)
+
+type sourceFactory struct {
+	name      string
+	newSource func() CTLSource
+}
+
+func init() {
+	Register(MOZILLA, func() CTLSource { return NewMozillaCTL() })
+	Register(APPLE, func() CTLSource { return NewAppleCTL() })
+	Register(MICROSOFT, func() CTLSource { return NewMicrosoftCTL() })
+	Register(CHROME, func() CTLSource { return NewChromeCTL() })
+	Register(OPENJDK, func() CTLSource { return NewOpenJDKCTL() })
+	Register(INTERNAL, func() CTLSource { return NewInternalCTL() })
+}
+
+// Register makes a CTL source available by name, e.g. to ctlcheck's -vendor
+// option. newSource returns a new, not yet fetched source whose Name is name.
+// It panics if name is empty or already registered.
+func Register(name string, newSource func() CTLSource) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	if name == "" || newSource == nil {
+		panic("ctl: Register of an empty name or a nil source")
+	}
+	for _, s := range sources {
+		if s.name == name {
+			panic("ctl: Register called twice for source " + name)
+		}
+	}
+	sources = append(sources, sourceFactory{name: name, newSource: newSource})
+}
+
+// Sources returns the names of the registered CTL sources, the built-in ones
+// first, in the order they were registered.
+func Sources() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	names := []string{}
+	for _, s := range sources {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// NewSource returns a new source of the registered CTL source name
+func NewSource(name string) (CTLSource, error) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	for _, s := range sources {
+		if s.name == name {
+			return s.newSource(), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown CTL source %q", name)
+}
@@ -0,0 +1,78 @@
+package ctl
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// testSource is a CTLSource as implemented outside of this package
+type testSource struct {
+	*CTL `yaml:",inline"`
+}
+
+func (s *testSource) Name() string   { return "test" }
+func (s *testSource) Vendor() string { return "Test" }
+func (s *testSource) Fetch(ctx context.Context, cl *http.Client) error {
+	s.Trusted["AA"] = Entry{Name: "fetched"}
+	return nil
+}
+
+func (s *testSource) Verify(certs []*Cert, allowedCerts Entrys) *VerifyResult {
+	return s.VerifyAs(s.Vendor(), certs, allowedCerts)
+}
+func (s *testSource) TrustList() *CTL { return s.CTL }
+
+// registerTest registers testSource once, tests may run more than once
+var registerTest sync.Once
+
+func TestRegister(t *testing.T) {
+	registerTest.Do(func() {
+		Register("test", func() CTLSource { return &testSource{CTL: NewCTL()} })
+	})
+
+	names := Sources()
+	want := []string{MOZILLA, APPLE, MICROSOFT, CHROME, OPENJDK, INTERNAL, "test"}
+	if len(names) != len(want) {
+		t.Fatalf("Sources() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Sources() = %v, want %v", names, want)
+		}
+	}
+	for _, name := range names {
+		s, err := NewSource(name)
+		if err != nil || s.Name() != name {
+			t.Errorf("NewSource(%q) = %v, %v", name, s, err)
+		}
+	}
+	if _, err := NewSource("missing"); err == nil {
+		t.Errorf("NewSource() of an unknown name, want error")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() twice, want panic")
+		}
+	}()
+	Register(MOZILLA, func() CTLSource { return NewMozillaCTL() })
+}
+
+func TestCTL_VerifyAs(t *testing.T) {
+	trusted := newValidCert(t, "Trusted Root")
+	removed := newValidCert(t, "Removed Root")
+
+	s := &testSource{CTL: NewCTL()}
+	s.Trusted[trusted.Checksum] = Entry{Name: "trusted"}
+	s.Removed[removed.Checksum] = Entry{Name: "removed", Status: "Revoked", Reason: "compromised"}
+	ret := s.Verify([]*Cert{trusted, removed}, Entrys{})
+
+	if ret.Vendor != "Test" || ret.Total != 2 || len(ret.TrustedCerts) != 1 || len(ret.RemovedCerts) != 1 {
+		t.Errorf("VerifyAs() = %+v, want 1 trusted and 1 removed cert of Test", ret)
+	}
+	if d := ret.certDetails(removed); len(d) == 0 {
+		t.Errorf("VerifyAs() details of the removed cert = %v, want its status and reason", d)
+	}
+}